@@ -53,7 +53,29 @@ func (f *FileCache) Set(key, value string, ttl time.Duration) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(cacheFileKey, cacheValue, os.ModePerm)
+	return writeFileAtomic(cacheFileKey, cacheValue)
+}
+
+// writeFileAtomic writes data to a temporary file readable only by its owner and renames it to filename,
+// so a file left by an earlier version with a wider mode is replaced rather than rewritten.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
 }
 
 func (f *FileCache) Get(key string) (string, error) {
@@ -3,6 +3,8 @@ package requests
 import (
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -32,6 +34,28 @@ func TestSetGet(t *testing.T) {
 	require.Equal(t, v, value)
 }
 
+// 测试缓存文件只有所有者可以读写
+func TestFileCacheMode(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewFileCache(dir).Set("key", "secret", 0))
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"+fileCacheSuffix))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// 旧版本写入的文件被替换
+	require.NoError(t, os.Chmod(files[0], os.ModePerm))
+	require.NoError(t, NewFileCache(dir).Set("key", "refreshed", 0))
+	info, err = os.Stat(files[0])
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	files, err = filepath.Glob(filepath.Join(dir, "*", "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
 func BenchmarkGet(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		key := fmt.Sprintf("key%d", i)
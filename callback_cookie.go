@@ -13,7 +13,7 @@ func onAfterRequestWithCookieGet(cache CacheInterface) RequestCallback {
 			if cookStr, _ := cache.Get(cacheKey); cookStr != "" {
 				var cookieRaw Cookie
 				_ = client.JSONUnmarshal([]byte(cookStr), &cookieRaw)
-				request.Header.Set(HttpHeaderCookie, cookieRaw.Encode())
+				addCookieHeader(request.Header, cookieRaw)
 			}
 		}
 		return nil
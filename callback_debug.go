@@ -1,7 +1,6 @@
 package requests
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,19 +8,27 @@ import (
 	"time"
 )
 
-const (
-	ctxDebugStartTime CtxKeyString = "_request_debug_logger_start_time"
-)
+// debugBodyLimit is the largest request body which is logged in debug mode.
+const debugBodyLimit = 1 << 20
 
 func onAfterRequestByDebug(client *Client, request *http.Request) error {
 	if client.Debug {
 		now := time.Now()
 		var body []byte
-		if request.Body != nil {
-			body, _ = io.ReadAll(request.Body)
-			request.Body = NewReadCloser(body, false)
+		if request.GetBody != nil && request.ContentLength >= 0 && request.ContentLength <= debugBodyLimit {
+			// Read a copy, so the body is still intact for sending and replaying.
+			if rc, err := request.GetBody(); err == nil {
+				body, _ = io.ReadAll(rc)
+				_ = rc.Close()
+				request.Body, _ = request.GetBody()
+			}
+		} else if hasBody(request) {
+			// Large and streamed bodies are not read, they would be buffered in memory.
+			body = []byte(fmt.Sprintf("[stream body, %d bytes]", request.ContentLength))
+		}
+		if r := requestFromContext(request.Context()); r != nil {
+			r.startTime = now
 		}
-		client.ctx = context.WithValue(context.Background(), ctxDebugStartTime, now)
 		headers, _ := client.JSONMarshal(request.Header)
 		reqLog := "\n==============================================================================\n" +
 			"~~~ REQUEST ~~~\n" +
@@ -41,22 +48,25 @@ func onResponseByDebug(client *Client, request *http.Request, response *Response
 		var responseBody []byte
 		if response.Body != nil {
 			responseBody, _ = io.ReadAll(response.Body)
+			_ = response.Body.Close()
 			response.Body = NewReadCloser(responseBody, false)
 		}
-		s := client.ctx.Value(ctxDebugStartTime).(time.Time)
+		s, attempt := e, 0
+		if r := requestFromContext(request.Context()); r != nil {
+			s, attempt = r.startTime, r.Attempt
+		}
 		headers, _ := client.JSONMarshal(response.Header)
 		debugLog := "~~~ RESPONSE ~~~\n" +
 			fmt.Sprintf("CLONE        : %v\n", client.clone) +
 			fmt.Sprintf("STATUS       : %s\n", response.Status) +
 			fmt.Sprintf("PROTO        : %s\n", response.Proto) +
-			fmt.Sprintf("ATTEMPT      : %v\n", client.attempt) +
+			fmt.Sprintf("ATTEMPT      : %v\n", attempt) +
 			fmt.Sprintf("RECEIVED AT  : %v\n", e.Format(time.RFC3339Nano)) +
 			fmt.Sprintf("HEADERS      : \n%v\n", string(headers))
 		debugLog += fmt.Sprintf("RESPONSE BODY : \n%s\n", string(responseBody))
 		debugLog += fmt.Sprintf("TIME CONSUMING : %v\n", e.Sub(s))
 		debugLog += "==============================================================================\n"
 		client.Logger.Debugf(debugLog)
-		client.ctx = context.Background()
 	}
 	return nil
 }
@@ -67,7 +77,9 @@ func onResponseByDebugWriter(client *Client, request *http.Request, response *Re
 		builder.WriteString("REQUEST: \n")
 		builder.WriteString(fmt.Sprintf("%s %s %s \n", request.Method, request.URL.String(), request.Proto))
 		builder.WriteString(fmt.Sprintf("Clone: %d \n", client.clone))
-		builder.WriteString(fmt.Sprintf("Attempt: %d \n", client.attempt))
+		if r := requestFromContext(request.Context()); r != nil {
+			builder.WriteString(fmt.Sprintf("Attempt: %d \n", r.Attempt))
+		}
 		reqHeader := request.Header
 		for s := range reqHeader {
 			builder.WriteString(fmt.Sprintf("%s : %s \n", s, reqHeader.Get(s)))
@@ -83,6 +95,7 @@ func onResponseByDebugWriter(client *Client, request *http.Request, response *Re
 		var responseBody []byte
 		if response.Body != nil {
 			responseBody, _ = io.ReadAll(response.Body)
+			_ = response.Body.Close()
 			response.Body = NewReadCloser(responseBody, false)
 		}
 		builder.Write(responseBody)
@@ -36,6 +36,12 @@ const (
 	HttpHeaderConnection    = "Connection"
 	HttpHeaderContentType   = `Content-Type`
 	HttpHeaderContentLength = `Content-Length`
+	HttpHeaderRetryAfter    = `Retry-After`
+
+	HttpHeaderIdempotencyKey = `Idempotency-Key`
+
+	HttpHeaderRateLimitRemaining = `X-RateLimit-Remaining`
+	HttpHeaderRateLimitReset     = `X-RateLimit-Reset`
 
 	CharsetUTF8                          = "charset=UTF-8"
 	HttpHeaderContentTypeJson            = `application/json`
@@ -65,6 +71,8 @@ type Client struct {
 	BaseUrl       string
 	Query         url.Values
 	QueryKVs      []KVCallback
+	PathParams    map[string]string
+	RawPathParams map[string]string
 	Header        http.Header
 	HeaderKVs     []KVCallback
 	Cookie        Cookie
@@ -84,17 +92,35 @@ type Client struct {
 	successHooks           []SuccessHook
 	errorHooks             []ErrorHook
 	panicHooks             []ErrorHook
+	attemptHooks           []AttemptHook
+
+	retryPolicy RetryPolicy
+	hedgePolicy *HedgePolicy
+
+	idempotencyHeader  string
+	idempotencyKeyFunc func() string
+
+	queryEncoder   *QueryEncoder
+	queryStructs   []any
+	queryMergeMode QueryMergeMode
+
+	uploadProgress   ProgressFunc
+	downloadProgress ProgressFunc
 
-	retryCount    int
-	retryWaitTime time.Duration
-	attempt       int
+	compression *Compression
+	signers     []Signer
 
-	traceContext traceContext
-	trace        bool
+	codecs           map[string]Codec
+	forceContentType string
+
+	defaultScheme string
+	baseURLJoin   bool
+	loadBalancer  *LoadBalancer
+
+	trace bool
 
 	clone int
 	lock  sync.RWMutex
-	ctx   context.Context
 }
 
 // DefaultHttpClient
@@ -121,6 +147,7 @@ func DefaultHttpClient(localAddr net.Addr) *http.Client {
 		},
 		DisableKeepAlives: true,
 	}
+	transport.RegisterProtocol(HttpUnixSchemeName, newUnixTransport(transport))
 	return &http.Client{Transport: transport}
 }
 
@@ -146,8 +173,15 @@ func (c *Client) Clone() *Client {
 		c.Client = DefaultHttpClient(nil)
 	}
 	c.BaseUrl = ""
+	c.defaultScheme = HttpsSchemeName
+	c.loadBalancer = nil
 	c.Query = make(url.Values, 0)
 	c.QueryKVs = []KVCallback{}
+	c.queryEncoder = NewQueryEncoder()
+	c.queryStructs = nil
+	c.queryMergeMode = QueryMergeKeep
+	c.PathParams = make(map[string]string)
+	c.RawPathParams = make(map[string]string)
 	c.Header = make(http.Header, 0)
 	c.HeaderKVs = []KVCallback{}
 	c.Cookie = make(Cookie, 0)
@@ -175,16 +209,18 @@ func (c *Client) Clone() *Client {
 	c.successHooks = make([]SuccessHook, 0)
 	c.errorHooks = make([]ErrorHook, 0)
 	c.panicHooks = make([]ErrorHook, 0)
+	c.attemptHooks = make([]AttemptHook, 0)
 
-	c.retryCount = defaultRetryCount
-	c.retryWaitTime = defaultWaitTime
+	c.SetRetry(defaultRetryCount, defaultWaitTime)
 
-	c.traceContext = traceContext{}
 	c.trace = false
+	c.uploadProgress = nil
+	c.downloadProgress = nil
+	c.compression = nil
+	c.signers = nil
+	c.registerDefaultCodecs()
+	c.forceContentType = ""
 
-	if c.ctx == nil {
-		c.ctx = context.Background()
-	}
 	if c.Header.Get(HttpHeaderUserAgent) == "" {
 		c.WithUserAgent(defaultClientAgent)
 	}
@@ -192,7 +228,6 @@ func (c *Client) Clone() *Client {
 	c.OnAfterRequest(onAfterRequestByDebug)
 	c.OnResponse(onResponseByDebug)
 	c.OnResponse(onResponseByDebugWriter)
-	c.attempt = 1
 	c.clone += 1
 	return c
 }
@@ -218,6 +253,9 @@ func (c *Client) SetWriter(writer io.Writer) *Client {
 	return c
 }
 
+// SetBaseURL sets the base URL which relative request URIs are resolved against as RFC 3986 references,
+// e.g. "users" against "https://api.example.com/v1/" is "https://api.example.com/v1/users"
+// and "/users" is "https://api.example.com/users", see SetBaseURLJoin to join them to its path instead.
 func (c *Client) SetBaseURL(baseUrl string) *Client {
 	c.BaseUrl = baseUrl
 	return c
@@ -228,7 +266,11 @@ func (c *Client) SetQuery(query url.Values) *Client {
 	return c
 }
 
+// WithQueryKV adds a callback which sets one query parameter of every request.
+// It keeps its KVCallback signature, structs and maps are added with WithQueryStruct.
 func (c *Client) WithQueryKV(callback KVCallback) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.QueryKVs = append(c.QueryKVs, callback)
 	return c
 }
@@ -241,6 +283,8 @@ func (c *Client) SetHeader(header http.Header) *Client {
 	return c
 }
 func (c *Client) WithHeaderKV(callback KVCallback) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.HeaderKVs = append(c.HeaderKVs, callback)
 	return c
 }
@@ -273,10 +317,9 @@ func (c *Client) SetXMLUnmarshaler(unmarshaler func(data []byte, v any) error) *
 
 // SetRetry is a chaining function,
 // which sets retry count and interval when failure for next request.
+// Only transport errors are retried, use SetRetryPolicy for more control.
 func (c *Client) SetRetry(retryCount int, retryWaitTime time.Duration) *Client {
-	c.retryCount = retryCount
-	c.retryWaitTime = retryWaitTime
-	return c
+	return c.SetRetryPolicy(FixedRetryPolicy(retryCount, retryWaitTime, RetryOnError))
 }
 
 // SetTimeout sets the request timeout for the client.
@@ -363,12 +406,16 @@ func (c *Client) SetCheckRedirect(fn func(req *http.Request, via []*http.Request
 	c.CheckRedirect = fn
 }
 
-// Unmarshal content into object from JSON or XML
-func (c *Client) Unmarshal(contentType string, b []byte, d any) (err error) {
-	if IsJSONType(contentType) {
-		err = c.JSONUnmarshal(b, d)
-	} else if IsXMLType(contentType) {
-		err = c.XMLUnmarshal(b, d)
+// Unmarshal content into object with the codec of contentType, see RegisterCodec.
+// An empty content or content type, e.g. of a 204 response, leaves d unchanged,
+// otherwise it returns ErrUnsupportedContentType when no codec is registered for contentType.
+func (c *Client) Unmarshal(contentType string, b []byte, d any) error {
+	if len(b) == 0 || strings.TrimSpace(contentType) == "" {
+		return nil
+	}
+	codec, err := c.Codec(contentType)
+	if err != nil {
+		return err
 	}
-	return
+	return codec.Unmarshal(b, d)
 }
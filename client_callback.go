@@ -16,9 +16,9 @@ func (c *Client) OnBeforeRequest(callback ClientCallback) *Client {
 	c.beforeRequestCallbacks = append(c.beforeRequestCallbacks, callback)
 	return c
 }
-func (c *Client) doBeforeRequestCallbacks() error {
-	for _, fn := range c.beforeRequestCallbacks {
-		if err := fn(c); err != nil {
+func (r *ClientRequest) doBeforeRequestCallbacks() error {
+	for _, fn := range r.beforeRequestCallbacks {
+		if err := fn(r.client); err != nil {
 			return err
 		}
 	}
@@ -38,9 +38,9 @@ func (c *Client) OnAfterRequest(callback RequestCallback) *Client {
 	c.afterRequestCallbacks = append(c.afterRequestCallbacks, callback)
 	return c
 }
-func (c *Client) doAfterRequestCallbacks(request *http.Request) error {
-	for _, fn := range c.afterRequestCallbacks {
-		if err := fn(c, request); err != nil {
+func (r *ClientRequest) doAfterRequestCallbacks(request *http.Request) error {
+	for _, fn := range r.afterRequestCallbacks {
+		if err := fn(r.client, request); err != nil {
 			return err
 		}
 	}
@@ -60,9 +60,9 @@ func (c *Client) OnResponse(callback ResponseCallback) *Client {
 	c.responseCallbacks = append(c.responseCallbacks, callback)
 	return c
 }
-func (c *Client) doResponseCallbacks(request *http.Request, response *Response) error {
-	for _, fn := range c.responseCallbacks {
-		if err := fn(c, request, response); err != nil {
+func (r *ClientRequest) doResponseCallbacks(request *http.Request, response *Response) error {
+	for _, fn := range r.responseCallbacks {
+		if err := fn(r.client, request, response); err != nil {
 			return err
 		}
 	}
@@ -75,12 +75,14 @@ func (c *Client) doResponseCallbacks(request *http.Request, response *Response)
 // Out of the OnSuccess, OnError, OnInvalid, OnPanic callbacks, exactly one
 // set will be invoked for each call to Request.Execute() that comletes.
 func (c *Client) OnSuccess(h SuccessHook) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.successHooks = append(c.successHooks, h)
 	return c
 }
-func (c *Client) doSuccessHooks(resp *Response) {
-	for _, h := range c.successHooks {
-		h(c, resp)
+func (r *ClientRequest) doSuccessHooks(resp *Response) {
+	for _, h := range r.successHooks {
+		h(r.client, resp)
 	}
 }
 
@@ -99,6 +101,8 @@ func (c *Client) doSuccessHooks(resp *Response) {
 // Out of the OnSuccess, OnError, OnInvalid, OnPanic callbacks, exactly one
 // set will be invoked for each call to Request.Execute() that comletes.
 func (c *Client) OnError(h ErrorHook) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.errorHooks = append(c.errorHooks, h)
 	return c
 }
@@ -106,17 +110,17 @@ func (c *Client) OnError(h ErrorHook) *Client {
 // Helper to run errorHooks hooks.
 // It wraps the error in a ResponseError if the response is not nil
 // so hooks can access it.
-func (c *Client) doErrorHooks(request *http.Request, response *Response, err error) {
+func (r *ClientRequest) doErrorHooks(request *http.Request, response *Response, err error) {
 	if err != nil {
 		if response == nil {
 			err = &ResponseError{Response: response, Err: err}
 		}
-		for _, h := range c.errorHooks {
-			h(c, request, err)
+		for _, h := range r.errorHooks {
+			h(r.client, request, err)
 		}
 	} else {
-		for _, h := range c.successHooks {
-			h(c, response)
+		for _, h := range r.successHooks {
+			h(r.client, response)
 		}
 	}
 }
@@ -129,13 +133,57 @@ func (c *Client) doErrorHooks(request *http.Request, response *Response, err err
 // If an OnSuccess, OnError, or OnInvalid callback panics, then the exactly
 // one rule can be violated.
 func (c *Client) OnPanic(h ErrorHook) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.panicHooks = append(c.panicHooks, h)
 	return c
 }
 
 // Helper to run panicHooks hooks.
-func (c *Client) doPanicHooks(request *http.Request, err error) {
-	for _, h := range c.panicHooks {
-		h(c, request, err)
+func (r *ClientRequest) doPanicHooks(request *http.Request, err error) {
+	for _, h := range r.panicHooks {
+		h(r.client, request, err)
+	}
+}
+
+// OnAttempt method adds a hook that will be run before every attempt of a request is sent,
+// retries included, after the middlewares. A hedged attempt is a single attempt.
+// An error stops the request and its retries before the attempt is sent, otherwise
+// the returned done func, when not nil, is called with the result of the attempt.
+//
+//	client.OnAttempt(func(c *requests.Client, request *http.Request) (func(*requests.Response, error), error) {
+//		start := time.Now()
+//		return func(response *requests.Response, err error) {
+//			log.Printf("%s %s: %v", request.Method, request.URL, time.Since(start))
+//		}, nil
+//	})
+func (c *Client) OnAttempt(h AttemptHook) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.attemptHooks = append(c.attemptHooks, h)
+	return c
+}
+
+// Helper to run attemptHooks hooks, it returns the done funcs of the attempt.
+// When a hook fails, the done funcs of the hooks before it are called with its error.
+func (r *ClientRequest) doBeforeAttemptHooks(request *http.Request) ([]func(*Response, error), error) {
+	var dones []func(*Response, error)
+	for _, h := range r.attemptHooks {
+		done, err := h(r.client, request)
+		if err != nil {
+			doAfterAttemptHooks(dones, nil, err)
+			return nil, err
+		}
+		if done != nil {
+			dones = append(dones, done)
+		}
+	}
+	return dones, nil
+}
+
+// Helper to run the done funcs of an attempt, in reverse order.
+func doAfterAttemptHooks(dones []func(*Response, error), response *Response, err error) {
+	for i := len(dones) - 1; i >= 0; i-- {
+		dones[i](response, err)
 	}
 }
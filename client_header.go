@@ -39,12 +39,14 @@ func (c *Client) WithHeaderMap(headers map[string]string) *Client {
 //
 // Also you can override header value, which was set at client instance level.
 func (c *Client) WithHeaderVerbatim(k, v string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.Header[k] = []string{v}
 	return c
 }
 
-// WithContentType is a chaining function,
-// which sets HTTP content type for the next request.
+// WithContentType sets the default content type of every later request of the client.
+// It changes the shared client, use ClientRequest.WithContentType to set it for one request.
 func (c *Client) WithContentType(contentType string) *Client {
 	c.WithHeader(HttpHeaderContentType, contentType)
 	return c
@@ -63,15 +65,17 @@ func (c *Client) WithRandomMobileUserAgent() *Client {
 	return c
 }
 
-// AsForm is a chaining function,
-// which sets the HTTP content type as "application/x-www-form-urlencoded" for the next request.
+// AsForm sets "application/x-www-form-urlencoded" as the default content type of every later request
+// of the client. It changes the shared client, use ClientRequest.AsForm to set it for one request.
 func (c *Client) AsForm() *Client {
 	c.WithContentType(HttpHeaderContentTypeForm)
 	return c
 }
 
-// AsJson is a chaining function,
-// which sets the HTTP content type as "application/json" for the next request.
+// AsJson sets "application/json" as the default content type of every later request of the client.
+// It changes the shared client, use ClientRequest.AsJson to set it for one request:
+//
+//	client.NewRequest(ctx, http.MethodPost, "/users", user).AsJson().Do()
 //
 // Note that it also checks and encodes the parameter to JSON format automatically.
 func (c *Client) AsJson() *Client {
@@ -79,8 +83,8 @@ func (c *Client) AsJson() *Client {
 	return c
 }
 
-// AsXml is a chaining function,
-// which sets the HTTP content type as "application/xml" for the next request.
+// AsXml sets "application/xml" as the default content type of every later request of the client.
+// It changes the shared client, use ClientRequest.AsXml to set it for one request.
 //
 // Note that it also checks and encodes the parameter to XML format automatically.
 func (c *Client) AsXml() *Client {
@@ -103,6 +107,6 @@ func (c *Client) WithToken(token string, authorizationType ...string) *Client {
 	} else {
 		token = AuthorizationTypeBearer + token
 	}
-	c.Header.Set(HttpHeaderAuthorization, token)
+	c.WithHeader(HttpHeaderAuthorization, token)
 	return c
 }
@@ -0,0 +1,257 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const hedgeLatencySamples = 100
+
+// HedgePolicy sends a duplicate of an idempotent request when the previous
+// attempt has not responded within a delay. The first response wins and the
+// other attempts are cancelled.
+//
+//	client.SetHedgePolicy(&requests.HedgePolicy{
+//		Delay:      50 * time.Millisecond,
+//		Percentile: 0.95,
+//	})
+//
+// Requests with a body are buffered in memory before they are hedged.
+//
+// The latencies of the attempts which got a response are sampled for Percentile, the attempts
+// cancelled before responding are not, so the percentile leans towards the faster responses.
+type HedgePolicy struct {
+	// Delay before another attempt is sent.
+	Delay time.Duration
+	// Percentile, e.g. 0.95, uses that percentile of the recent response latencies
+	// as delay once enough responses were seen, Delay is used until then.
+	Percentile float64
+	// MaxAttempts is the maximum number of attempts in flight, including the first one,
+	// 2 when zero.
+	MaxAttempts int
+	// Methods are the hedged methods, GET and HEAD when empty.
+	Methods []string
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+type hedgeResult struct {
+	index    int
+	attempt  int
+	response *http.Response
+	err      error
+	trace    *traceContext
+	cancel   context.CancelFunc
+	latency  time.Duration
+}
+
+// SetHedgePolicy enables hedged requests, a nil policy disables them.
+func (c *Client) SetHedgePolicy(policy *HedgePolicy) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hedgePolicy = policy
+	return c
+}
+
+// SetHedgePolicy overrides the client hedge policy for this request,
+// e.g. to hedge a request whose method is not hedged by default but is idempotent.
+func (r *ClientRequest) SetHedgePolicy(policy *HedgePolicy) *ClientRequest {
+	r.hedgePolicy = policy
+	return r
+}
+
+func (p *HedgePolicy) hedges(method string) bool {
+	if len(p.Methods) == 0 {
+		return method == http.MethodGet || method == http.MethodHead
+	}
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *HedgePolicy) maxAttempts() int {
+	if p.MaxAttempts > 1 {
+		return p.MaxAttempts
+	}
+	return 2
+}
+
+func (p *HedgePolicy) delay() time.Duration {
+	if p.Percentile <= 0 || p.Percentile > 1 {
+		return p.Delay
+	}
+	p.mu.Lock()
+	samples := append([]time.Duration(nil), p.latencies...)
+	p.mu.Unlock()
+	if len(samples) < hedgeLatencySamples/10 {
+		return p.Delay
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[int(float64(len(samples)-1)*p.Percentile)]
+}
+
+func (p *HedgePolicy) observe(latency time.Duration) {
+	if p.Percentile <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.latencies) < hedgeLatencySamples {
+		p.latencies = append(p.latencies, latency)
+		return
+	}
+	p.latencies[p.next] = latency
+	p.next = (p.next + 1) % hedgeLatencySamples
+}
+
+// send sends one attempt of request, hedging it when the policy of r allows it.
+func (r *ClientRequest) send(request *http.Request) (*http.Response, error) {
+	r.attempts++
+	r.Attempt, r.hedged = r.attempts, false
+	policy := r.hedgePolicy
+	if policy == nil || !policy.hedges(request.Method) {
+		if err := r.sign(request); err != nil {
+			return nil, err
+		}
+		return r.client.Do(request)
+	}
+	var body []byte
+	if hasBody(request) {
+		b, err := io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	results := make(chan hedgeResult, policy.maxAttempts())
+	launched := make([]*hedgeResult, 0, policy.maxAttempts())
+	launch := func() {
+		res := &hedgeResult{index: len(launched), attempt: r.attempts}
+		var ctx context.Context
+		if res.index == 0 {
+			ctx, res.trace = request.Context(), r.traceContext
+		} else {
+			r.attempts++
+			res.attempt = r.attempts
+			ctx = request.Context()
+			if r.traceHooks != nil {
+				ctx = hedgeContext{Context: ctx, skip: r.traceHooks, base: r.ctx}
+			}
+			if r.trace {
+				res.trace = &traceContext{}
+				ctx = res.trace.createContext(ctx)
+			}
+		}
+		ctx, res.cancel = context.WithCancel(ctx)
+		req := request.Clone(ctx)
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		launched = append(launched, res)
+		start := time.Now()
+		go func() {
+			var response *http.Response
+			err := r.sign(req)
+			if err == nil {
+				response, err = r.client.Do(req)
+			}
+			results <- hedgeResult{index: res.index, response: response, err: err, latency: time.Since(start)}
+		}()
+	}
+	launch()
+	timer := time.NewTimer(policy.delay())
+	defer timer.Stop()
+	var (
+		winner  *hedgeResult
+		lastErr error
+		pending = 1
+	)
+	for winner == nil && pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err != nil {
+				lastErr = res.err
+				continue
+			}
+			winner = launched[res.index]
+			winner.response, winner.latency = res.response, res.latency
+		case <-timer.C:
+			if len(launched) < policy.maxAttempts() {
+				launch()
+				pending++
+				timer.Reset(policy.delay())
+			}
+		}
+	}
+	for _, res := range launched {
+		if res != winner {
+			res.cancel()
+		}
+	}
+	if pending > 0 {
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if res := <-results; res.response != nil {
+					// The attempt responded before it was cancelled.
+					policy.observe(res.latency)
+					_ = res.response.Body.Close()
+				}
+			}
+		}(pending)
+	}
+	if winner == nil {
+		return nil, lastErr
+	}
+	policy.observe(winner.latency)
+	r.Attempt, r.hedged = winner.attempt, winner.index > 0
+	if winner.trace != nil {
+		r.traceContext = winner.trace
+	}
+	winner.response.Body = &cancelReadCloser{ReadCloser: winner.response.Body, cancel: winner.cancel}
+	return winner.response, nil
+}
+
+// hedgeContext is the context of a hedged attempt, it has the values and the deadline
+// of the request context except the trace hooks of the first attempt, which are looked up in base instead.
+type hedgeContext struct {
+	context.Context
+	skip *httptrace.ClientTrace
+	base context.Context
+}
+
+func (c hedgeContext) Value(key any) any {
+	v := c.Context.Value(key)
+	if trace, ok := v.(*httptrace.ClientTrace); ok && trace == c.skip {
+		return c.base.Value(key)
+	}
+	return v
+}
+
+// cancelReadCloser cancels the context of a hedged attempt once its body is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelReadCloser) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
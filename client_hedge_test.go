@@ -0,0 +1,116 @@
+package requests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgePolicy(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("hedged"))
+	}))
+	defer server.Close()
+
+	client := New().SetRetry(0, 0).EnableTrace().SetHedgePolicy(&HedgePolicy{Delay: 20 * time.Millisecond})
+	start := time.Now()
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	defer response.Close()
+	require.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	require.Equal(t, "hedged", response.ReadAllString())
+	require.Equal(t, 2, response.TraceInfo().RequestAttempt)
+	require.True(t, response.TraceInfo().Hedged)
+}
+
+func TestHedgePolicySkipsUnsafeMethods(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := New().SetRetry(0, 0).SetHedgePolicy(&HedgePolicy{Delay: time.Millisecond})
+	response, err := client.Post(context.Background(), server.URL, "k=v")
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestHedgePolicyKeepsRequestContext(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("hedged"))
+	}))
+	defer server.Close()
+
+	type key struct{}
+	var mu sync.Mutex
+	var values []any
+	var firstHooks int32
+	client := New().SetRetry(0, 0).EnableTrace().SetHedgePolicy(&HedgePolicy{Delay: 20 * time.Millisecond}).
+		Use(func(c *Client, r *http.Request) (*Response, error) {
+			return c.Next(r.WithContext(context.WithValue(r.Context(), key{}, "tenant")))
+		}).
+		WithSigner(SignerFunc(func(request *http.Request) error {
+			mu.Lock()
+			defer mu.Unlock()
+			values = append(values, request.Context().Value(key{}))
+			if len(values) == 1 {
+				// Count the trace events of the first attempt.
+				trace := httptrace.ContextClientTrace(request.Context())
+				getConn := trace.GetConn
+				trace.GetConn = func(hostPort string) {
+					atomic.AddInt32(&firstHooks, 1)
+					getConn(hostPort)
+				}
+			}
+			return nil
+		}))
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, "hedged", response.ReadAllString())
+	require.Equal(t, []any{"tenant", "tenant"}, values)
+	require.Equal(t, int32(1), atomic.LoadInt32(&firstHooks))
+	require.True(t, response.TraceInfo().Hedged)
+}
+
+func TestHedgePolicySignsBufferedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Body-Sha256")))
+	}))
+	defer server.Close()
+
+	signer := NewHMACSigner("secret")
+	signer.BodyHashHeader = "X-Body-Sha256"
+	client := New().SetRetry(0, 0).WithSigner(signer).
+		SetHedgePolicy(&HedgePolicy{Delay: time.Second, Methods: []string{http.MethodPut}})
+	// A streamed body is buffered, the signers of the attempts can read it again.
+	response, err := client.Put(context.Background(), server.URL, io.NopCloser(strings.NewReader("hello")))
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, Sha256("hello"), response.ReadAllString())
+}
@@ -0,0 +1,69 @@
+package requests
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// NewIdempotencyKey returns a random UUID (version 4), the default idempotency key generator.
+func NewIdempotencyKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// EnableIdempotencyKey sends an `Idempotency-Key` header with a random UUID on
+// retried POST and PATCH requests, see SetIdempotencyKey.
+func (c *Client) EnableIdempotencyKey() *Client {
+	return c.SetIdempotencyKey(HttpHeaderIdempotencyKey, NewIdempotencyKey)
+}
+
+// SetIdempotencyKey sets the header name and the generator of idempotency keys.
+//
+// When the retry policy can retry, that is a BackoffRetryPolicy with MaxRetries above zero
+// or a custom RetryPolicy, every POST and PATCH request gets one key from generator,
+// which is sent unchanged with every attempt of that request, so the server can
+// recognize retries of the same call. A key already set on the request is kept.
+// An empty header disables it.
+//
+//	client.SetIdempotencyKey("X-Request-Id", func() string {
+//		return xid.New().String()
+//	})
+func (c *Client) SetIdempotencyKey(header string, generator func() string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.idempotencyHeader = header
+	c.idempotencyKeyFunc = generator
+	return c
+}
+
+// WithIdempotencyKey sets the idempotency key of this request.
+func (r *ClientRequest) WithIdempotencyKey(key string) *ClientRequest {
+	r.client.lock.RLock()
+	header := r.client.idempotencyHeader
+	r.client.lock.RUnlock()
+	if header == "" {
+		header = HttpHeaderIdempotencyKey
+	}
+	return r.WithHeader(header, key)
+}
+
+// setIdempotencyKey adds an idempotency key to header when the request needs one.
+func (r *ClientRequest) setIdempotencyKey(header http.Header, name string, generator func() string) {
+	if name == "" || !canRetry(r.retryPolicy) {
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		return
+	}
+	if header.Get(name) != "" {
+		return
+	}
+	if generator == nil {
+		generator = NewIdempotencyKey
+	}
+	header.Set(name, generator())
+}
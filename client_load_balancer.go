@@ -0,0 +1,307 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BalanceStrategy is how a LoadBalancer selects the endpoint of a request.
+type BalanceStrategy int
+
+const (
+	// BalanceRoundRobin selects the endpoints in turn.
+	BalanceRoundRobin BalanceStrategy = iota
+	// BalanceRandom selects a random endpoint.
+	BalanceRandom
+	// BalanceLeastInFlight selects the endpoint with the fewest requests in flight.
+	BalanceLeastInFlight
+	// BalanceWeighted selects the endpoints in proportion to their weight, smoothly interleaved.
+	BalanceWeighted
+)
+
+const (
+	defaultBalancerCooldown = 30 * time.Second
+)
+
+// EndpointStats is a snapshot of one endpoint of a LoadBalancer.
+type EndpointStats struct {
+	URL      string
+	Weight   int
+	InFlight int
+	Healthy  bool
+}
+
+// LoadBalancer spreads requests with a relative URI over several base URLs.
+//
+// An endpoint which fails MaxFailures times in a row is ejected for Cooldown,
+// and every retry of a request is sent to another endpoint when there is one.
+//
+//	lb := requests.NewLoadBalancer("http://10.0.0.1:8080", "http://10.0.0.2:8080")
+//	lb.Strategy = requests.BalanceLeastInFlight
+//	lb.HealthCheck(ctx, http.DefaultClient, "/healthz", 10*time.Second)
+//	client.SetLoadBalancer(lb)
+type LoadBalancer struct {
+	Strategy BalanceStrategy
+	// MaxFailures is the number of consecutive failures which eject an endpoint, 1 when zero.
+	MaxFailures int
+	// Cooldown is how long an endpoint is ejected, 30 seconds when zero.
+	Cooldown time.Duration
+	// IsFailure reports whether an attempt failed, transport errors and 5xx responses by default.
+	IsFailure func(response *http.Response, err error) bool
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+type endpoint struct {
+	url          string
+	weight       int
+	current      int
+	inFlight     int
+	failures     int
+	ejectedUntil time.Time
+	down         bool
+}
+
+// NewLoadBalancer creates a round-robin LoadBalancer over urls.
+func NewLoadBalancer(urls ...string) *LoadBalancer {
+	lb := &LoadBalancer{}
+	for _, u := range urls {
+		lb.AddEndpoint(u, 1)
+	}
+	return lb
+}
+
+// AddEndpoint adds a base URL with a weight for BalanceWeighted.
+func (lb *LoadBalancer) AddEndpoint(url string, weight int) *LoadBalancer {
+	if weight <= 0 {
+		weight = 1
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.endpoints = append(lb.endpoints, &endpoint{url: url, weight: weight})
+	return lb
+}
+
+// Endpoints returns the stats of every endpoint.
+func (lb *LoadBalancer) Endpoints() []EndpointStats {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	now := time.Now()
+	stats := make([]EndpointStats, 0, len(lb.endpoints))
+	for _, e := range lb.endpoints {
+		stats = append(stats, EndpointStats{URL: e.url, Weight: e.weight, InFlight: e.inFlight, Healthy: e.healthy(now)})
+	}
+	return stats
+}
+
+// SetBaseURLs balances the requests with a relative URI round-robin over urls,
+// see SetLoadBalancer for the other strategies.
+func (c *Client) SetBaseURLs(urls ...string) *Client {
+	return c.SetLoadBalancer(NewLoadBalancer(urls...))
+}
+
+// SetLoadBalancer balances the requests with a relative URI over the endpoints of lb,
+// instead of the BaseUrl. A nil lb uses the BaseUrl again.
+func (c *Client) SetLoadBalancer(lb *LoadBalancer) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.loadBalancer = lb
+	return c
+}
+
+// HealthCheck probes every endpoint with a GET of path every interval until ctx is done,
+// a probe times out after interval. An endpoint is taken out of rotation while its probe fails, a 2xx or 3xx response brings it back.
+func (lb *LoadBalancer) HealthCheck(ctx context.Context, client *http.Client, path string, interval time.Duration) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			lb.probe(ctx, client, path, interval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (lb *LoadBalancer) probe(ctx context.Context, client *http.Client, path string, timeout time.Duration) {
+	lb.mu.Lock()
+	endpoints := append([]*endpoint(nil), lb.endpoints...)
+	lb.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			healthy := false
+			if u, err := resolveURL(e.url, path, "", false); err == nil {
+				probeCtx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				if request, err := http.NewRequestWithContext(probeCtx, http.MethodGet, u.String(), nil); err == nil {
+					if response, err := client.Do(request); err == nil {
+						_ = response.Body.Close()
+						healthy = response.StatusCode < 400
+					}
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			lb.mu.Lock()
+			e.down = !healthy
+			if healthy {
+				e.failures, e.ejectedUntil = 0, time.Time{}
+			}
+			lb.mu.Unlock()
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (e *endpoint) healthy(now time.Time) bool {
+	return !e.down && !now.Before(e.ejectedUntil)
+}
+
+// pick selects an endpoint other than exclude when possible and counts it in flight.
+// When every endpoint is ejected, they are all used again rather than failing the request.
+func (lb *LoadBalancer) pick(exclude *endpoint) *endpoint {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if len(lb.endpoints) == 0 {
+		return nil
+	}
+	now := time.Now()
+	candidates := make([]*endpoint, 0, len(lb.endpoints))
+	for _, e := range lb.endpoints {
+		if e.healthy(now) && e != exclude {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, e := range lb.endpoints {
+			if e.healthy(now) {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = lb.endpoints
+	}
+	var chosen *endpoint
+	switch lb.Strategy {
+	case BalanceRandom:
+		chosen = candidates[rand.Intn(len(candidates))]
+	case BalanceLeastInFlight:
+		start := lb.next % len(candidates)
+		lb.next++
+		for i := range candidates {
+			e := candidates[(start+i)%len(candidates)]
+			if chosen == nil || e.inFlight < chosen.inFlight {
+				chosen = e
+			}
+		}
+	case BalanceWeighted:
+		total := 0
+		for _, e := range candidates {
+			e.current += e.weight
+			total += e.weight
+			if chosen == nil || e.current > chosen.current {
+				chosen = e
+			}
+		}
+		chosen.current -= total
+	default:
+		chosen = candidates[lb.next%len(candidates)]
+		lb.next++
+	}
+	chosen.inFlight++
+	return chosen
+}
+
+// done records the result of an attempt sent to e.
+func (lb *LoadBalancer) done(e *endpoint, response *http.Response, err error) {
+	failed := lb.isFailure(response, err)
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	e.inFlight--
+	if !failed {
+		e.failures = 0
+		return
+	}
+	e.failures++
+	maxFailures := lb.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+	if e.failures >= maxFailures {
+		cooldown := lb.Cooldown
+		if cooldown <= 0 {
+			cooldown = defaultBalancerCooldown
+		}
+		e.ejectedUntil = time.Now().Add(cooldown)
+		e.failures = 0
+	}
+}
+
+func (lb *LoadBalancer) isFailure(response *http.Response, err error) bool {
+	if lb.IsFailure != nil {
+		return lb.IsFailure(response, err)
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return response.StatusCode >= http.StatusInternalServerError
+}
+
+// release takes e out of flight without recording a result, for an attempt which was never sent.
+func (lb *LoadBalancer) release(e *endpoint) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	e.inFlight--
+}
+
+// balance sends the current attempt of request to an endpoint of the load balancer of r,
+// the endpoint picked by prepare for the first attempt and another one for every retry.
+// It returns the endpoint whose result has to be reported.
+func (r *ClientRequest) balance(request *http.Request) (*endpoint, error) {
+	if r.loadBalancer == nil {
+		return nil, nil
+	}
+	if r.endpointPending {
+		r.endpointPending = false
+		return r.endpoint, nil
+	}
+	e := r.loadBalancer.pick(r.endpoint)
+	if e == nil {
+		return nil, nil
+	}
+	u, err := resolveURL(e.url, r.relativeURI, r.scheme, r.baseURLJoin)
+	if err != nil {
+		r.loadBalancer.release(e)
+		return nil, err
+	}
+	r.endpoint = e
+	u.RawQuery = request.URL.RawQuery
+	request.URL = u
+	if request.Header.Get(HttpHeaderHost) == "" {
+		request.Host = u.Host
+	}
+	return e, nil
+}
+
+// isRelativeURI reports whether uri is resolved against a base URL.
+func isRelativeURI(uri string) bool {
+	return !schemeRegexp.MatchString(uri) && !strings.HasPrefix(uri, "//")
+}
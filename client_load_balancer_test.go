@@ -0,0 +1,82 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancerFailover(t *testing.T) {
+	var failing int32 = 1
+	newServer := func(name string, fail bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail && atomic.LoadInt32(&failing) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(name + r.URL.Path))
+		}))
+	}
+	a, b := newServer("a", true), newServer("b", false)
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(a.URL+"/v1", b.URL+"/v1")
+	// The URIs of the retries are joined to the path of the endpoints too.
+	client := New().SetLoadBalancer(lb).SetBaseURLJoin(true).SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus))
+	var got []string
+	for i := 0; i < 3; i++ {
+		body, err := client.GetBytes(context.Background(), "/users", nil)
+		require.NoError(t, err)
+		got = append(got, string(body))
+	}
+	// a fails once and is ejected, the retry and the next requests go to b.
+	require.Equal(t, []string{"b/v1/users", "b/v1/users", "b/v1/users"}, got)
+	stats := lb.Endpoints()
+	require.False(t, stats[0].Healthy)
+	require.True(t, stats[1].Healthy)
+	require.Zero(t, stats[0].InFlight+stats[1].InFlight)
+
+	// Absolute URIs are not balanced.
+	body, err := client.GetBytes(context.Background(), b.URL+"/direct", nil)
+	require.NoError(t, err)
+	require.Equal(t, "b/direct", string(body))
+
+	atomic.StoreInt32(&failing, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lb.HealthCheck(ctx, nil, "/healthz", time.Hour)
+	require.Eventually(t, func() bool { return lb.Endpoints()[0].Healthy }, time.Second, 10*time.Millisecond)
+}
+
+func TestLoadBalancerStrategies(t *testing.T) {
+	picks := func(lb *LoadBalancer, n int) string {
+		var names []string
+		for i := 0; i < n; i++ {
+			e := lb.pick(nil)
+			names = append(names, e.url)
+			lb.release(e)
+		}
+		return strings.Join(names, "")
+	}
+	require.Equal(t, "abcabc", picks(NewLoadBalancer("a", "b", "c"), 6))
+
+	weighted := NewLoadBalancer().AddEndpoint("a", 3).AddEndpoint("b", 1)
+	weighted.Strategy = BalanceWeighted
+	require.Equal(t, "aabaaaba", picks(weighted, 8))
+
+	least := NewLoadBalancer("a", "b", "c")
+	least.Strategy = BalanceLeastInFlight
+	busy := least.pick(nil)
+	require.Equal(t, "a", busy.url)
+	require.Equal(t, "b", least.pick(nil).url)
+	require.Equal(t, "c", least.pick(nil).url)
+	least.release(busy)
+	require.Equal(t, "a", least.pick(nil).url)
+}
@@ -20,6 +20,8 @@ type clientMiddleware struct {
 
 // Use adds one or more middleware handlers to client.
 func (c *Client) Use(middlewares ...MiddlewareFunc) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.middlewares = append(c.middlewares, middlewares...)
 	return c
 }
@@ -36,13 +38,13 @@ func (c *Client) Next(req *http.Request) (*Response, error) {
 }
 
 // Next calls the next middleware handler.
+// A handler may call Next again, e.g. to replay the request after a 401, which runs the next handlers again.
 func (m *clientMiddleware) Next(req *http.Request) (response *Response, err error) {
-	if m.err != nil {
-		return m.response, m.err
-	}
-	if m.handlerIndex < len(m.handlers) {
+	index := m.handlerIndex
+	if index+1 < len(m.handlers) {
 		m.handlerIndex++
 		m.response, m.err = m.handlers[m.handlerIndex](m.client, req)
+		m.handlerIndex = index
 	}
 	return m.response, m.err
 }
@@ -0,0 +1,74 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Header.Get("X-Trace"))
+	}))
+	defer server.Close()
+
+	var calls []string
+	trace := func(name string) MiddlewareFunc {
+		return func(c *Client, r *http.Request) (*Response, error) {
+			calls = append(calls, name)
+			r.Header.Set("X-Trace", r.Header.Get("X-Trace")+name)
+			return c.Next(r)
+		}
+	}
+	response, err := New().Use(trace("a"), trace("b")).Use(trace("c")).Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	require.Equal(t, "abc", response.ReadAllString())
+	require.Equal(t, []string{"a", "b", "c"}, calls)
+
+	// A middleware which does not call Next answers the request itself.
+	errStop := errors.New("stop")
+	_, err = New().Use(func(c *Client, r *http.Request) (*Response, error) {
+		return nil, errStop
+	}, trace("unreachable")).Get(context.Background(), server.URL, nil)
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, []string{"a", "b", "c"}, calls)
+}
+
+func TestClientMiddlewareReplay(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get(HttpHeaderAuthorization) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, r.Header.Get("X-Inner"))
+	}))
+	defer server.Close()
+
+	inner := 0
+	response, err := New().Use(func(c *Client, r *http.Request) (*Response, error) {
+		response, err := c.Next(r)
+		if err != nil || response.StatusCode != http.StatusUnauthorized {
+			return response, err
+		}
+		// Calling Next again runs the next middlewares and sends the request again.
+		discardBody(response)
+		r.Header.Set(HttpHeaderAuthorization, "Bearer token")
+		return c.Next(r)
+	}, func(c *Client, r *http.Request) (*Response, error) {
+		inner++
+		r.Header.Set("X-Inner", strings.Repeat("i", inner))
+		return c.Next(r)
+	}).Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "ii", response.ReadAllString())
+	require.Equal(t, 2, attempts)
+}
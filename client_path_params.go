@@ -0,0 +1,130 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrPathParamMissing = errors.New("missing path parameter")
+)
+
+// pathParam is the value of a `{name}` placeholder in a request URI.
+type pathParam struct {
+	value string
+	raw   bool
+}
+
+// SetPathParam sets a path parameter which replaces `{k}` in the URI of every request, escaped.
+//
+//	client.SetPathParam("id", "john doe").Get(ctx, "/users/{id}", nil)
+//	// GET /users/john%20doe
+func (c *Client) SetPathParam(k, v string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.PathParams[k] = v
+	return c
+}
+
+// SetPathParams sets multiple path parameters, see SetPathParam.
+func (c *Client) SetPathParams(params map[string]string) *Client {
+	for k, v := range params {
+		c.SetPathParam(k, v)
+	}
+	return c
+}
+
+// SetRawPathParam sets a path parameter which replaces `{k}` in the URI of every request unescaped.
+//
+//	client.SetRawPathParam("path", "docs/index.html").Get(ctx, "/files/{path}", nil)
+//	// GET /files/docs/index.html
+func (c *Client) SetRawPathParam(k, v string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.RawPathParams[k] = v
+	return c
+}
+
+// SetRawPathParams sets multiple raw path parameters, see SetRawPathParam.
+func (c *Client) SetRawPathParams(params map[string]string) *Client {
+	for k, v := range params {
+		c.SetRawPathParam(k, v)
+	}
+	return c
+}
+
+// SetPathParam sets an escaped path parameter for this request only.
+func (r *ClientRequest) SetPathParam(k, v string) *ClientRequest {
+	r.PathParams[k] = v
+	return r
+}
+
+// SetPathParams sets multiple escaped path parameters for this request only.
+func (r *ClientRequest) SetPathParams(params map[string]string) *ClientRequest {
+	for k, v := range params {
+		r.SetPathParam(k, v)
+	}
+	return r
+}
+
+// SetRawPathParam sets an unescaped path parameter for this request only.
+func (r *ClientRequest) SetRawPathParam(k, v string) *ClientRequest {
+	r.RawPathParams[k] = v
+	return r
+}
+
+// SetRawPathParams sets multiple unescaped path parameters for this request only.
+func (r *ClientRequest) SetRawPathParams(params map[string]string) *ClientRequest {
+	for k, v := range params {
+		r.SetRawPathParam(k, v)
+	}
+	return r
+}
+
+// addPathParams adds params to the layered path parameters.
+func addPathParams(layered map[string]pathParam, params map[string]string, raw bool) {
+	for k, v := range params {
+		layered[k] = pathParam{value: v, raw: raw}
+	}
+}
+
+// expandPathParams replaces every `{name}` placeholder in the path of uri with its parameter,
+// braces in the query or fragment, e.g. ?filter={"a":1}, are sent as they are.
+func expandPathParams(uri string, params map[string]pathParam) (string, error) {
+	suffix := ""
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri, suffix = uri[:i], uri[i:]
+	}
+	if !strings.Contains(uri, "{") {
+		return uri + suffix, nil
+	}
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(uri, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(uri[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		name := uri[start+1 : end]
+		param, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf(`%w "%s" in "%s"`, ErrPathParamMissing, name, uri)
+		}
+		buf.WriteString(uri[:start])
+		if param.raw {
+			buf.WriteString(param.value)
+		} else {
+			buf.WriteString(url.PathEscape(param.value))
+		}
+		uri = uri[end+1:]
+	}
+	buf.WriteString(uri)
+	buf.WriteString(suffix)
+	return buf.String(), nil
+}
@@ -0,0 +1,61 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPathParams(t *testing.T) {
+	params := map[string]pathParam{
+		"id":   {value: "a/b c"},
+		"repo": {value: "go-requests"},
+		"path": {value: "docs/index.html", raw: true},
+	}
+	for _, test := range []struct {
+		input   string
+		expect  string
+		wantErr bool
+	}{
+		{"/users/{id}/repos/{repo}", "/users/a%2Fb%20c/repos/go-requests", false},
+		{"/files/{path}", "/files/docs/index.html", false},
+		{"/users", "/users", false},
+		{"/users/{id", "/users/{id", false},
+		{`/users/{repo}?filter={"a":1}#{frag}`, `/users/go-requests?filter={"a":1}#{frag}`, false},
+		{`/search?q={name}`, `/search?q={name}`, false},
+		{"/users/{name}", "", true},
+	} {
+		result, err := expandPathParams(test.input, params)
+		if test.wantErr {
+			require.ErrorIs(t, err, ErrPathParamMissing)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, test.expect, result)
+	}
+}
+
+func TestClientPathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.EscapedPath() + r.URL.Query().Get("filter")))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetPathParam("user", "pkg6")
+	body, err := client.NewRequest(context.Background(), http.MethodGet, "/users/{user}/repos/{repo}", nil).
+		SetPathParam("repo", "go requests/v1").
+		DoBytes()
+	require.NoError(t, err)
+	require.Equal(t, "/users/pkg6/repos/go%20requests%2Fv1", string(body))
+
+	_, err = client.Get(context.Background(), "/users/{user}/repos/{repo}", nil)
+	require.ErrorIs(t, err, ErrPathParamMissing)
+
+	// Braces in the query are not placeholders.
+	body, err = client.GetBytes(context.Background(), `/users/{user}?filter={"a":1}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, `/users/pkg6{"a":1}`, string(body))
+}
@@ -61,7 +61,7 @@ func (c *Client) Trace(ctx context.Context, uri string, data any) (*Response, er
 }
 
 func (c *Client) PostJson(ctx context.Context, uri string, data any) (*Response, error) {
-	return c.AsJson().Post(ctx, uri, data)
+	return c.NewRequest(ctx, http.MethodPost, uri, data).AsJson().Do()
 }
 
 // PostForm is different from net/http.PostForm.
@@ -78,12 +78,9 @@ func (c *Client) PostJson(ctx context.Context, uri string, data any) (*Response,
 //
 // none
 //
-// It's used for sending form data.
+// It's used for sending form data, values prefixed with `@file:` are uploaded as files,
+// use a Multipart body for readers and custom part headers.
 // Note that the response object MUST be closed if it'll never be used.
 func (c *Client) PostForm(ctx context.Context, uri string, data url.Values) (*Response, error) {
-	w, body, err := buildFormBody(data)
-	if err != nil {
-		return nil, err
-	}
-	return c.WithContentType(w.FormDataContentType()).Post(ctx, uri, body)
+	return c.NewRequest(ctx, http.MethodPost, uri, multipartFromValues(data)).Do()
 }
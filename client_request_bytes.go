@@ -34,12 +34,8 @@ func (c *Client) TraceBytes(ctx context.Context, uri string, data any) ([]byte,
 	return c.DoRequestBytes(ctx, http.MethodTrace, uri, data)
 }
 func (c *Client) PostJsonBytes(ctx context.Context, uri string, data any) ([]byte, error) {
-	return c.AsJson().DoRequestBytes(ctx, http.MethodPost, uri, data)
+	return c.NewRequest(ctx, http.MethodPost, uri, data).AsJson().DoBytes()
 }
 func (c *Client) PostFormBytes(ctx context.Context, uri string, data url.Values) ([]byte, error) {
-	w, body, err := buildFormBody(data)
-	if err != nil {
-		return nil, err
-	}
-	return c.WithContentType(w.FormDataContentType()).PostBytes(ctx, uri, body)
+	return c.NewRequest(ctx, http.MethodPost, uri, multipartFromValues(data)).DoBytes()
 }
@@ -34,12 +34,8 @@ func (c *Client) TraceD(ctx context.Context, uri string, data, d any) (response
 	return c.DoRequestD(ctx, http.MethodTrace, uri, data, d)
 }
 func (c *Client) PostJsonD(ctx context.Context, uri string, data, d any) (response *Response, err error) {
-	return c.AsJson().DoRequestD(ctx, http.MethodPost, uri, data, d)
+	return c.NewRequest(ctx, http.MethodPost, uri, data).AsJson().DoD(d)
 }
 func (c *Client) PostFormD(ctx context.Context, uri string, data url.Values, d any) (response *Response, err error) {
-	w, body, err := buildFormBody(data)
-	if err != nil {
-		return nil, err
-	}
-	return c.WithContentType(w.FormDataContentType()).PostD(ctx, uri, body, d)
+	return c.NewRequest(ctx, http.MethodPost, uri, multipartFromValues(data)).DoD(d)
 }
@@ -7,16 +7,41 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 	"time"
 )
 
 func (c *Client) DoRequestUnmarshal(ctx context.Context, method string, uri string, data, d any) error {
-	_, err := c.DoRequestD(ctx, method, uri, data, d)
-	return err
+	return c.NewRequest(ctx, method, uri, data).DoUnmarshal(d)
 }
 func (c *Client) DoRequestD(ctx context.Context, method string, uri string, data, d any) (response *Response, err error) {
-	response, err = c.DoRequest(ctx, method, uri, data)
+	return c.NewRequest(ctx, method, uri, data).DoD(d)
+}
+func (c *Client) DoRequestBytes(ctx context.Context, method string, uri string, data any) ([]byte, error) {
+	return c.NewRequest(ctx, method, uri, data).DoBytes()
+}
+func (c *Client) DoRequest(ctx context.Context, method, uri string, body any) (response *Response, err error) {
+	return c.NewRequest(ctx, method, uri, body).Do()
+}
+
+// callRequest sends request, which was not built by a Request, with the client retry policy.
+func (c *Client) callRequest(request *http.Request) (*Response, error) {
+	r := requestFromContext(request.Context())
+	if r == nil {
+		r = c.NewRequest(request.Context(), request.Method, request.URL.String(), nil)
+		request = request.WithContext(context.WithValue(request.Context(), requestContextKey, r))
+		r.RawRequest = request
+	}
+	return r.callRequest(request)
+}
+
+func (r *ClientRequest) DoUnmarshal(d any) error {
+	_, err := r.DoD(d)
+	return err
+}
+func (r *ClientRequest) DoD(d any) (response *Response, err error) {
+	response, err = r.Do()
 	if err != nil {
 		return
 	}
@@ -24,14 +49,14 @@ func (c *Client) DoRequestD(ctx context.Context, method string, uri string, data
 		_ = response.Close()
 	}()
 	if response.IsError() {
-		err = &RequestError{StatusCode: response.StatusCode, Method: method, URI: uri, Response: response}
+		err = &RequestError{StatusCode: response.StatusCode, Method: r.Method, URI: r.URI, Response: response}
 		return
 	}
 	err = response.Unmarshal(d)
 	return
 }
-func (c *Client) DoRequestBytes(ctx context.Context, method string, uri string, data any) ([]byte, error) {
-	response, err := c.DoRequest(ctx, method, uri, data)
+func (r *ClientRequest) DoBytes() ([]byte, error) {
+	response, err := r.Do()
 	if err != nil {
 		return nil, err
 	}
@@ -39,40 +64,51 @@ func (c *Client) DoRequestBytes(ctx context.Context, method string, uri string,
 		_ = response.Close()
 	}()
 	if response.IsError() {
-		return nil, &RequestError{StatusCode: response.StatusCode, Method: method, URI: uri, Response: response}
+		return nil, &RequestError{StatusCode: response.StatusCode, Method: r.Method, URI: r.URI, Response: response}
 	}
 	return response.ReadAll(), nil
 }
 
-func (c *Client) DoRequest(ctx context.Context, method, uri string, body any) (response *Response, err error) {
-	if err = c.doBeforeRequestCallbacks(); err != nil {
+// Do sends the request and returns the response object.
+// Note that the response object MUST be closed if it'll never be used.
+func (r *ClientRequest) Do() (response *Response, err error) {
+	c := r.client
+	if err = r.doBeforeRequestCallbacks(); err != nil {
 		return nil, err
 	}
-	request, err := c.prepareRequest(ctx, method, uri, body)
+	defer func() {
+		if r.endpointPending {
+			// The request failed before it was sent.
+			r.endpointPending = false
+			r.loadBalancer.release(r.endpoint)
+		}
+	}()
+	request, err := r.prepare()
 	defer func() {
 		if rec := recover(); rec != nil {
 			if panicErr, ok := rec.(error); ok {
-				c.doPanicHooks(request, panicErr)
+				r.doPanicHooks(request, panicErr)
 				panic(panicErr)
 			}
 		}
 	}()
 	if err != nil {
-		c.doErrorHooks(request, nil, err)
+		r.doErrorHooks(request, nil, err)
 		return nil, err
 	}
-	if err = c.doAfterRequestCallbacks(request); err != nil {
-		c.doErrorHooks(request, nil, err)
+	if err = r.doAfterRequestCallbacks(request); err != nil {
+		r.doErrorHooks(request, nil, err)
 		return nil, err
 	}
+	setUploadProgress(request, r.uploadProgress)
 	// Client middleware.
-	if len(c.middlewares) > 0 {
-		middlewares := make([]MiddlewareFunc, 0, len(c.middlewares)+1)
-		middlewares = append(middlewares, c.middlewares...)
-		middlewares = append(middlewares, func(cli *Client, r *http.Request) (*Response, error) {
-			return cli.callRequest(r)
+	if len(r.middlewares) > 0 {
+		middlewares := make([]MiddlewareFunc, 0, len(r.middlewares)+1)
+		middlewares = append(middlewares, r.middlewares...)
+		middlewares = append(middlewares, func(cli *Client, req *http.Request) (*Response, error) {
+			return r.callRequest(req)
 		})
-		ctx = context.WithValue(request.Context(),
+		ctx := context.WithValue(request.Context(),
 			clientMiddlewareKey,
 			&clientMiddleware{
 				client:       c,
@@ -82,132 +118,261 @@ func (c *Client) DoRequest(ctx context.Context, method, uri string, body any) (r
 		request = request.WithContext(ctx)
 		response, err = c.Next(request)
 	} else {
-		response, err = c.callRequest(request)
+		response, err = r.callRequest(request)
 	}
 	if err != nil {
-		c.doErrorHooks(request, response, err)
+		r.doErrorHooks(request, response, err)
 		return nil, err
 	}
-	err = c.doResponseCallbacks(request, response)
+	err = r.doResponseCallbacks(request, response)
 	if err != nil {
-		c.doErrorHooks(request, response, err)
+		r.doErrorHooks(request, response, err)
 		return nil, err
 	}
-	c.doSuccessHooks(response)
+	r.doSuccessHooks(response)
 	return response, err
 }
-func (c *Client) callRequest(request *http.Request) (response *Response, err error) {
-	response = &Response{request: request, client: c}
-	for {
-		if response.Response, err = c.Do(request); err != nil {
-			c.attempt++
+
+func (r *ClientRequest) callRequest(request *http.Request) (response *Response, err error) {
+	c := r.client
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if rewindErr := rewindBody(request); rewindErr != nil {
+				return nil, fmt.Errorf(`retry attempt %d: %w`, attempt, rewindErr)
+			}
+		}
+		e, balanceErr := r.balance(request)
+		if balanceErr != nil {
+			return nil, balanceErr
+		}
+		dones, attemptErr := r.doBeforeAttemptHooks(request)
+		if attemptErr != nil {
+			if e != nil {
+				r.loadBalancer.release(e)
+			}
+			return nil, fmt.Errorf(`client.Do: %w`, attemptErr)
+		}
+		response = &Response{request: request, client: c, req: r}
+		response.Response, err = r.send(request)
+		if e != nil {
+			r.loadBalancer.done(e, response.Response, err)
+		}
+		if r.traceContext != nil {
+			r.traceContext.endTime = time.Now()
+		}
+		if err != nil {
 			// The response might not be nil when err != nil.
 			if response.Response != nil {
 				_ = response.Response.Body.Close()
 			}
-			if c.retryCount > 0 {
-				c.retryCount--
-				time.Sleep(c.retryWaitTime)
-			} else {
-				break
-			}
-			return response, fmt.Errorf(fmt.Sprintf(`client.Do: %v`, err))
-		} else {
+			response = nil
+		} else if redirectErr := checkRedirectBody(request, response.Response); redirectErr != nil {
+			_ = response.Response.Body.Close()
+			doAfterAttemptHooks(dones, nil, redirectErr)
+			return nil, redirectErr
+		}
+		doAfterAttemptHooks(dones, response, err)
+		if r.retryPolicy == nil {
 			break
 		}
+		wait, retry := r.retryPolicy.Apply(RetryAttempt{
+			Attempt:  attempt,
+			Elapsed:  time.Since(start),
+			Request:  request,
+			Response: response,
+			Err:      err,
+		})
+		if !retry || (hasBody(request) && request.GetBody == nil) {
+			// A streamed body has been consumed and cannot be sent again.
+			break
+		}
+		discardBody(response)
+		if sleepErr := sleepContext(request.Context(), wait); sleepErr != nil {
+			if err == nil {
+				err = sleepErr
+			}
+			return nil, fmt.Errorf(`client.Do: %w`, err)
+		}
 	}
-	return response, err
+	if err != nil {
+		return nil, fmt.Errorf(`client.Do: %w`, err)
+	}
+	setDownloadProgress(response.Response, r.downloadProgress)
+	return response, nil
 }
-func (c *Client) prepareBodyDefault(method string, body any) string {
+func (c *Client) prepareBodyDefault(method string, body any, encoder *QueryEncoder) (string, error) {
 	switch val := body.(type) {
 	case []byte:
-		return string(val)
+		return string(val), nil
 	case string:
-		return val
+		return val, nil
 	case url.Values:
-		return val.Encode()
+		return val.Encode(), nil
 	case *url.Values:
-		return val.Encode()
+		return val.Encode(), nil
 	case bytes.Buffer:
-		return val.String()
+		return val.String(), nil
 	case *bytes.Buffer:
-		return val.String()
+		return val.String(), nil
 	case strings.Builder:
-		return val.String()
+		return val.String(), nil
 	case map[string]string:
 		uv := url.Values{}
 		for s, s2 := range val {
 			uv.Set(s, s2)
 		}
-		return uv.Encode()
+		return uv.Encode(), nil
 	}
-	if method == http.MethodGet && body != nil {
-		if jsonByte, err := c.JSONMarshal(body); err == nil {
-			mapAny := make(map[string]any)
-			if err = c.JSONUnmarshal(jsonByte, &mapAny); err == nil {
-				return HttpBuildQuery(mapAny)
-			}
-		}
+	if method == http.MethodGet && isQueryObject(indirectValue(reflect.ValueOf(body))) {
+		return encoder.Encode(body)
 	}
-	return AnyString(body)
+	return AnyString(body), nil
 }
-func (c *Client) prepareBody(method string, body any) (string, error) {
-	var params string
-	if body != nil {
-		contentType := c.Header.Get(HttpHeaderContentType)
-		if IsJSONType(contentType) {
-			switch body.(type) {
-			case string, []byte:
-				params = AnyString(body)
-			default:
-				if b, err := c.JSONMarshal(body); err != nil {
-					return "", err
-				} else {
-					params = string(b)
-				}
-			}
-		} else if IsXMLType(contentType) {
-			switch body.(type) {
-			case string, []byte:
-				params = AnyString(body)
-			default:
-				if b, err := c.XMLMarshal(body); err != nil {
-					return "", err
-				} else {
-					params = string(b)
-				}
-			}
-		} else {
-			params = c.prepareBodyDefault(method, body)
+
+// prepareBody encodes body with the codec of contentType, see RegisterCodec.
+// Raw bodies and bodies without a registered codec are encoded as they are, or as query for GET.
+func (c *Client) prepareBody(method, contentType string, body any, encoder *QueryEncoder) (string, error) {
+	if body == nil {
+		return "", nil
+	}
+	if isRawBody(body) {
+		return c.prepareBodyDefault(method, body, encoder)
+	}
+	if contentType != "" {
+		if codec, err := c.Codec(contentType); err == nil {
+			b, err := codec.Marshal(body)
+			return string(b), err
 		}
 	}
-	return params, nil
+	return c.prepareBodyDefault(method, body, encoder)
 }
 
-func (c *Client) prepareRequest(ctx context.Context, method, uri string, body any) (request *http.Request, err error) {
-	method = strings.ToUpper(method)
-	if len(c.BaseUrl) > 0 {
-		uri = c.BaseUrl + strings.Trim(uri, "")
+// isRawBody reports whether body is sent as it is, without a codec.
+func isRawBody(body any) bool {
+	switch body.(type) {
+	case string, []byte, bytes.Buffer, *bytes.Buffer, strings.Builder:
+		return true
+	}
+	return false
+}
+
+// prepare builds the http.Request of r.
+// Headers, query and cookies are layered as client defaults, client kv callbacks,
+// the defaults removed for the request and then the values set on the request itself.
+// Every request gets its own copies, so middlewares and callbacks only mutate their request.
+func (r *ClientRequest) prepare() (request *http.Request, err error) {
+	c := r.client
+	method, uri := r.Method, r.URI
+	c.lock.RLock()
+	header := c.Header.Clone()
+	query := cloneValues(c.Query)
+	cookie := c.Cookie.Clone()
+	baseUrl, scheme, join, loadBalancer := c.BaseUrl, c.defaultScheme, c.baseURLJoin, c.loadBalancer
+	idempotencyHeader, idempotencyKeyFunc := c.idempotencyHeader, c.idempotencyKeyFunc
+	queryEncoder := c.queryEncoder
+	if queryEncoder == nil {
+		queryEncoder = NewQueryEncoder()
+	}
+	queryStructs := append(append([]any(nil), c.queryStructs...), r.queryStructs...)
+	pathParams := make(map[string]pathParam)
+	addPathParams(pathParams, c.PathParams, false)
+	addPathParams(pathParams, c.RawPathParams, true)
+	c.lock.RUnlock()
+	addPathParams(pathParams, r.PathParams, false)
+	addPathParams(pathParams, r.RawPathParams, true)
+	if uri, err = expandPathParams(uri, pathParams); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		header = make(http.Header)
+	}
+	for _, callback := range r.headerKVs {
+		k, v := callback()
+		header.Set(k, v)
+	}
+	for _, k := range r.removedHeaders {
+		header.Del(k)
+		if http.CanonicalHeaderKey(k) == HttpHeaderUserAgent {
+			// An empty User-Agent stops net/http from sending its default one.
+			header.Set(HttpHeaderUserAgent, "")
+		}
 	}
-	if !strings.Contains(uri, HttpSchemeName) {
-		uri = HttpSchemeName + "://" + uri
+	for k, v := range r.Header {
+		header[k] = append([]string(nil), v...)
 	}
-	q := []url.Values{c.Query}
-	kv := url.Values{}
-	for _, callback := range c.QueryKVs {
+	r.setIdempotencyKey(header, idempotencyHeader, idempotencyKeyFunc)
+	for _, callback := range r.queryKVs {
 		k, v := callback()
-		kv.Set(k, v)
+		query.Set(k, v)
 	}
-	q = append(q, kv)
-	uri = URIQuery(uri, q...).String()
-	params, err := c.prepareBody(method, body)
+	for _, v := range queryStructs {
+		values, err := queryEncoder.Values(v)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range values {
+			query[k] = vs
+		}
+	}
+	for k, v := range r.Query {
+		query[k] = append([]string(nil), v...)
+	}
+	contentType := header.Get(HttpHeaderContentType)
+	var params string
+	reader, stream := streamBody(r.Body)
+	form, multipartBody := r.Body.(*Multipart)
+	if !stream && !multipartBody {
+		if params, err = c.prepareBody(method, contentType, r.Body, queryEncoder); err != nil {
+			return nil, err
+		}
+	}
+	var bodyQuery url.Values
+	if method == http.MethodGet && params != "" && !isRawBody(r.Body) && !IsJSONType(contentType) && !IsXMLType(contentType) {
+		// Struct, map and url.Values parameters are encoded once with the query, sorted by key,
+		// and added to the values of the same keys whatever the query merge mode.
+		if values, err := url.ParseQuery(params); err == nil {
+			bodyQuery, params = values, ""
+		}
+	}
+	for _, k := range r.removedCookies {
+		cookie.Del(k)
+	}
+	for k, v := range r.Cookie {
+		cookie[k] = append([]string(nil), v...)
+	}
+	if loadBalancer != nil && isRelativeURI(uri) {
+		if e := loadBalancer.pick(nil); e != nil {
+			r.loadBalancer, r.endpoint, r.endpointPending = loadBalancer, e, true
+			r.relativeURI, r.scheme, r.baseURLJoin = uri, scheme, join
+			baseUrl = e.url
+		}
+	}
+	u, err := resolveURL(baseUrl, uri, scheme, join)
 	if err != nil {
 		return nil, err
 	}
-	if method == http.MethodGet {
+	u.RawQuery = mergeRawQueryAdd(u.RawQuery, r.queryMergeMode, query, bodyQuery)
+	uri = u.String()
+	if multipartBody {
+		if request, err = http.NewRequest(method, uri, nil); err != nil {
+			return nil, fmt.Errorf(`http.NewRequest failed for method "%s" and URL "%s"`, method, uri)
+		}
+		if err = setMultipartBody(request, form); err != nil {
+			return nil, err
+		}
+		header.Set(HttpHeaderContentType, form.ContentType())
+	} else if stream {
+		// Readers go to the wire as they are, see setRequestBody.
+		if request, err = http.NewRequest(method, uri, nil); err != nil {
+			return nil, fmt.Errorf(`http.NewRequest failed for method "%s" and URL "%s"`, method, uri)
+		}
+		if err = setRequestBody(request, reader); err != nil {
+			return nil, err
+		}
+	} else if method == http.MethodGet {
 		var bodyBuffer *bytes.Buffer
 		if params != "" {
-			contentType := c.Header.Get(HttpHeaderContentType)
 			if IsJSONType(contentType) || IsXMLType(contentType) {
 				bodyBuffer = bytes.NewBuffer([]byte(params))
 			} else {
@@ -221,47 +386,44 @@ func (c *Client) prepareRequest(ctx context.Context, method, uri string, body an
 		} else {
 			bodyBuffer = bytes.NewBuffer(nil)
 		}
-		if request, err = http.NewRequest(method, uri, bodyBuffer); err != nil {
+		if request, err = http.NewRequest(method, uri, nil); err != nil {
 			return nil, fmt.Errorf(`http.NewRequest failed with method "%s" and URL "%s"`, method, uri)
 		}
+		if err = setRequestBody(request, bodyBuffer); err != nil {
+			return nil, err
+		}
 	} else {
 		paramBytes := []byte(params)
-		if request, err = http.NewRequest(method, uri, bytes.NewReader(paramBytes)); err != nil {
+		if request, err = http.NewRequest(method, uri, nil); err != nil {
 			return nil, fmt.Errorf(`http.NewRequest failed for method "%s" and URL "%s"`, method, uri)
-		} else {
-			if v := c.Header.Get(HttpHeaderContentType); v != "" {
-				// Custom Content-Type.
-				request.Header.Set(HttpHeaderContentType, v)
-			} else if len(paramBytes) > 0 {
-				if (paramBytes[0] == '[' || paramBytes[0] == '{') && json.Valid(paramBytes) {
-					// Auto-detecting and setting the post content format: JSON.
-					request.Header.Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
-				} else if IsMatchString(HttpRegexParamJson, params) {
-					// If the parameters passed like "name=value", it then uses form type.
-					request.Header.Set(HttpHeaderContentType, HttpHeaderContentTypeForm)
-				}
+		} else if err = setRequestBody(request, bytes.NewReader(paramBytes)); err != nil {
+			return nil, err
+		} else if contentType == "" && len(paramBytes) > 0 {
+			if (paramBytes[0] == '[' || paramBytes[0] == '{') && json.Valid(paramBytes) {
+				// Auto-detecting and setting the post content format: JSON.
+				header.Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+			} else if IsMatchString(HttpRegexParamJson, params) {
+				// If the parameters passed like "name=value", it then uses form type.
+				header.Set(HttpHeaderContentType, HttpHeaderContentTypeForm)
 			}
 		}
 	}
 	//Load Context
-	if withContext := c.withContext(ctx); withContext != nil {
-		request = request.WithContext(withContext)
-	}
+	request = request.WithContext(context.WithValue(r.withContext(r.ctx), requestContextKey, r))
 	//Load cookies
-	if len(c.Cookie) > 0 {
-		c.Header.Set(HttpHeaderCookie, c.Cookie.Encode())
-	}
-	//kv callback
-	for _, callback := range c.HeaderKVs {
-		k, v := callback()
-		c.Header.Set(k, v)
+	if len(cookie) > 0 {
+		addCookieHeader(header, cookie)
 	}
 	// Custom header.
-	if len(c.Header) > 0 {
-		request.Header = c.Header
+	request.Header = header
+	if r.compression != nil {
+		if err = r.compression.apply(request, stream || multipartBody); err != nil {
+			return nil, err
+		}
 	}
 	if reqHeaderHost := request.Header.Get(HttpHeaderHost); reqHeaderHost != "" {
 		request.Host = reqHeaderHost
 	}
+	r.RawRequest = request
 	return request, nil
 }
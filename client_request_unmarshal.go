@@ -34,12 +34,8 @@ func (c *Client) TraceUnmarshal(ctx context.Context, uri string, data, d any) er
 	return c.DoRequestUnmarshal(ctx, http.MethodTrace, uri, data, d)
 }
 func (c *Client) PostJsonUnmarshal(ctx context.Context, uri string, data, d any) error {
-	return c.AsJson().DoRequestUnmarshal(ctx, http.MethodPost, uri, data, d)
+	return c.NewRequest(ctx, http.MethodPost, uri, data).AsJson().DoUnmarshal(d)
 }
 func (c *Client) PostFormUnmarshal(ctx context.Context, uri string, data url.Values, d any) error {
-	w, body, err := buildFormBody(data)
-	if err != nil {
-		return err
-	}
-	return c.WithContentType(w.FormDataContentType()).PostUnmarshal(ctx, uri, body, d)
+	return c.NewRequest(ctx, http.MethodPost, uri, multipartFromValues(data)).DoUnmarshal(d)
 }
@@ -0,0 +1,270 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// RetryCondition reports whether an attempt that finished with the given
+	// response or error should be retried.
+	// The response is nil when the attempt failed with an error.
+	RetryCondition func(response *Response, err error) bool
+
+	// RetryAttempt describes the attempt that has just finished.
+	RetryAttempt struct {
+		// Attempt is the 1-based number of the finished attempt.
+		Attempt int
+		// Elapsed is the time passed since the first attempt was sent.
+		Elapsed time.Duration
+		// Request is the request that was sent.
+		Request *http.Request
+		// Response is the response of the attempt, nil when Err is not nil.
+		Response *Response
+		// Err is the error returned by the underlying http.Client.
+		Err error
+	}
+
+	// RetryPolicy to regulate the retries in the client.
+	//
+	// Apply is called after every attempt and returns how long to wait before
+	// the next attempt and whether another attempt should be made at all.
+	RetryPolicy interface {
+		Apply(attempt RetryAttempt) (time.Duration, bool)
+	}
+
+	// The RetryPolicyFunc type is an adapter to allow the use of ordinary functions as RetryPolicy.
+	// If f is a function with the appropriate signature, RetryPolicyFunc(f) is a RetryPolicy object that calls f.
+	RetryPolicyFunc func(attempt RetryAttempt) (time.Duration, bool)
+)
+
+// Apply calls f(attempt).
+func (f RetryPolicyFunc) Apply(attempt RetryAttempt) (time.Duration, bool) {
+	return f(attempt)
+}
+
+var (
+	// RetryOnTemporaryStatus retries responses whose status usually means the
+	// server is temporarily unable to handle the request.
+	RetryOnTemporaryStatus = RetryOnStatus(
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	)
+)
+
+// RetryOnError retries attempts that failed with a transport error.
+// Canceled or expired contexts are never retried.
+func RetryOnError(response *Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryOnStatus retries responses with one of the given status codes.
+func RetryOnStatus(statusCodes ...int) RetryCondition {
+	codes := make(map[int]bool, len(statusCodes))
+	for _, code := range statusCodes {
+		codes[code] = true
+	}
+	return func(response *Response, err error) bool {
+		if response == nil || response.Response == nil {
+			return false
+		}
+		return codes[response.StatusCode]
+	}
+}
+
+// DefaultRetryConditions returns the conditions used by the built-in policies
+// when none are given: transport errors and RetryOnTemporaryStatus.
+func DefaultRetryConditions() []RetryCondition {
+	return []RetryCondition{RetryOnError, RetryOnTemporaryStatus}
+}
+
+// BackoffRetryPolicy is the built-in RetryPolicy.
+// The wait before retry n is WaitTime * Multiplier^(n-1), capped by MaxWaitTime.
+type BackoffRetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	MaxRetries int
+	// WaitTime is the wait before the first retry.
+	WaitTime time.Duration
+	// MaxWaitTime caps a single wait, zero means no cap.
+	MaxWaitTime time.Duration
+	// Multiplier grows the wait on every retry, values below 1 keep it fixed.
+	Multiplier float64
+	// Jitter randomizes every wait between half and the full computed wait.
+	Jitter bool
+	// MaxElapsedTime stops retrying once the next attempt would start later
+	// than MaxElapsedTime after the first one, zero means no limit.
+	MaxElapsedTime time.Duration
+	// RespectRetryAfter uses the Retry-After response header, in seconds or
+	// as an HTTP-date, instead of the computed wait when it is present.
+	// The header is capped by MaxWaitTime as well.
+	RespectRetryAfter bool
+	// Conditions decide which attempts are retried, DefaultRetryConditions when empty.
+	Conditions []RetryCondition
+}
+
+// Apply implements RetryPolicy.
+func (p *BackoffRetryPolicy) Apply(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Attempt > p.MaxRetries {
+		return 0, false
+	}
+	conditions := p.Conditions
+	if len(conditions) == 0 {
+		conditions = DefaultRetryConditions()
+	}
+	matched := false
+	for _, condition := range conditions {
+		if condition(attempt.Response, attempt.Err) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return 0, false
+	}
+	wait := p.backoff(attempt.Attempt)
+	if p.RespectRetryAfter && attempt.Response != nil && attempt.Response.Response != nil {
+		if retryAfter, ok := parseRetryAfter(attempt.Response.Header.Get(HttpHeaderRetryAfter)); ok {
+			wait = retryAfter
+			if p.MaxWaitTime > 0 && wait > p.MaxWaitTime {
+				wait = p.MaxWaitTime
+			}
+		}
+	}
+	if p.MaxElapsedTime > 0 && attempt.Elapsed+wait > p.MaxElapsedTime {
+		return 0, false
+	}
+	return wait, true
+}
+
+func (p *BackoffRetryPolicy) backoff(attempt int) time.Duration {
+	wait := float64(p.WaitTime)
+	if p.Multiplier > 1 {
+		wait *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	if p.MaxWaitTime > 0 && wait > float64(p.MaxWaitTime) {
+		wait = float64(p.MaxWaitTime)
+	}
+	if p.Jitter && wait > 0 {
+		wait = wait/2 + rand.Float64()*wait/2
+	}
+	return time.Duration(wait)
+}
+
+// NoRetryPolicy is used to disable retries in the HTTP client
+//
+//	client.SetRetryPolicy(requests.NoRetryPolicy())
+func NoRetryPolicy() RetryPolicy {
+	return noRetryPolicy{}
+}
+
+type noRetryPolicy struct{}
+
+func (noRetryPolicy) Apply(attempt RetryAttempt) (time.Duration, bool) {
+	return 0, false
+}
+
+// canRetry reports whether policy may retry a request, custom policies are assumed to.
+func canRetry(policy RetryPolicy) bool {
+	switch p := policy.(type) {
+	case nil, noRetryPolicy:
+		return false
+	case *BackoffRetryPolicy:
+		return p.MaxRetries > 0
+	}
+	return true
+}
+
+// FixedRetryPolicy retries up to retryCount times, waiting waitTime between attempts.
+// Retry-After is ignored, use a BackoffRetryPolicy with RespectRetryAfter and MaxWaitTime to follow it.
+//
+//	client.SetRetryPolicy(requests.FixedRetryPolicy(3, time.Second))
+func FixedRetryPolicy(retryCount int, waitTime time.Duration, conditions ...RetryCondition) RetryPolicy {
+	return &BackoffRetryPolicy{
+		MaxRetries: retryCount,
+		WaitTime:   waitTime,
+		Conditions: conditions,
+	}
+}
+
+// ExponentialRetryPolicy retries up to retryCount times, doubling the wait from waitTime
+// up to maxWaitTime with jitter. Retry-After is followed up to maxWaitTime.
+//
+//	client.SetRetryPolicy(requests.ExponentialRetryPolicy(5, 100*time.Millisecond, 10*time.Second))
+func ExponentialRetryPolicy(retryCount int, waitTime, maxWaitTime time.Duration, conditions ...RetryCondition) RetryPolicy {
+	return &BackoffRetryPolicy{
+		MaxRetries:        retryCount,
+		WaitTime:          waitTime,
+		MaxWaitTime:       maxWaitTime,
+		Multiplier:        2,
+		Jitter:            true,
+		RespectRetryAfter: true,
+		Conditions:        conditions,
+	}
+}
+
+// SetRetryPolicy sets the policy which decides whether and when a request is retried.
+// A nil policy disables retries.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.retryPolicy = policy
+	return c
+}
+
+// parseRetryAfter parses a Retry-After header value given in seconds or as an HTTP-date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discardBody drains and closes the body of a response that will be retried,
+// so the underlying connection can be reused.
+func discardBody(response *Response) {
+	if response == nil || response.Response == nil || response.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+	_ = response.Body.Close()
+}
@@ -0,0 +1,139 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyRetriesTemporaryStatus(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.Header().Set(HttpHeaderRetryAfter, "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New().SetRetryPolicy(ExponentialRetryPolicy(3, time.Millisecond, 10*time.Millisecond))
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "ok", response.ReadAllString())
+	require.Equal(t, int32(3), atomic.LoadInt32(&hits))
+}
+
+func TestRetryPolicyReturnsLastResponse(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := New().SetRetryPolicy(FixedRetryPolicy(2, time.Millisecond))
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, http.StatusBadGateway, response.StatusCode)
+	require.Equal(t, int32(3), atomic.LoadInt32(&hits))
+}
+
+func TestRetryReusesIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(HttpHeaderIdempotencyKey))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New().EnableIdempotencyKey().SetRetryPolicy(FixedRetryPolicy(2, time.Millisecond))
+	response, err := client.Post(context.Background(), server.URL, "k=v")
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Len(t, keys, 3)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+	require.Equal(t, keys[0], keys[2])
+
+	keys = nil
+	response, err = client.NewRequest(context.Background(), http.MethodPost, server.URL, "k=v").
+		WithIdempotencyKey("order-1").
+		Do()
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, []string{"order-1", "order-1", "order-1"}, keys)
+
+	// No key is generated when the request is not retried.
+	for _, policy := range []RetryPolicy{nil, NoRetryPolicy(), FixedRetryPolicy(0, time.Millisecond)} {
+		keys = []string{"", ""}
+		response, err = client.NewRequest(context.Background(), http.MethodPost, server.URL, "k=v").SetRetryPolicy(policy).Do()
+		require.NoError(t, err)
+		_ = response.Close()
+		require.Equal(t, []string{"", "", ""}, keys)
+	}
+}
+
+func TestBackoffRetryPolicyApply(t *testing.T) {
+	busy := &Response{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}}
+	retryAfter := &Response{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{HttpHeaderRetryAfter: {"0"}}}}
+	retryAfterDay := &Response{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{HttpHeaderRetryAfter: {"86400"}}}}
+	notFound := &Response{Response: &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}}}
+	policy := &BackoffRetryPolicy{
+		MaxRetries:        3,
+		WaitTime:          100 * time.Millisecond,
+		MaxWaitTime:       300 * time.Millisecond,
+		Multiplier:        2,
+		MaxElapsedTime:    time.Second,
+		RespectRetryAfter: true,
+	}
+	tests := []struct {
+		name    string
+		attempt RetryAttempt
+		want    time.Duration
+		retry   bool
+	}{
+		{name: "first", attempt: RetryAttempt{Attempt: 1, Response: busy}, want: 100 * time.Millisecond, retry: true},
+		{name: "second", attempt: RetryAttempt{Attempt: 2, Response: busy}, want: 200 * time.Millisecond, retry: true},
+		{name: "capped", attempt: RetryAttempt{Attempt: 3, Response: busy}, want: 300 * time.Millisecond, retry: true},
+		{name: "exhausted", attempt: RetryAttempt{Attempt: 4, Response: busy}},
+		{name: "retry-after", attempt: RetryAttempt{Attempt: 2, Response: retryAfter}, retry: true},
+		{name: "retry-after capped", attempt: RetryAttempt{Attempt: 1, Response: retryAfterDay}, want: 300 * time.Millisecond, retry: true},
+		{name: "status", attempt: RetryAttempt{Attempt: 1, Response: notFound}},
+		{name: "error", attempt: RetryAttempt{Attempt: 1, Err: errors.New("reset")}, want: 100 * time.Millisecond, retry: true},
+		{name: "canceled", attempt: RetryAttempt{Attempt: 1, Err: context.Canceled}},
+		{name: "elapsed", attempt: RetryAttempt{Attempt: 1, Elapsed: 950 * time.Millisecond, Response: busy}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, retry := policy.Apply(tt.attempt)
+			require.Equal(t, tt.retry, retry)
+			require.Equal(t, tt.want, wait)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	wait, ok := parseRetryAfter("120")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, wait)
+
+	wait, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.InDelta(t, float64(time.Hour), float64(wait), float64(2*time.Second))
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+}
@@ -8,25 +8,26 @@ import (
 	"time"
 )
 
-func (c *Client) withContext(ctx context.Context) context.Context {
-	if c.trace {
-		if ctx == nil {
-			ctx = c.traceContext.createContext(context.Background())
-		} else {
-			ctx = c.traceContext.createContext(ctx)
-		}
+func (r *ClientRequest) withContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if r.trace {
+		r.traceContext = &traceContext{}
+		ctx = r.traceContext.createContext(ctx)
+		r.traceHooks = r.traceContext.hooks
 	}
 	return ctx
 }
+
+// EnableTrace enables tracing for every request of the client.
 func (c *Client) EnableTrace() *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.trace = true
 	return c
 }
 
-func (c *Client) getTraceInfo() TraceInfo {
-	return c.traceContext.Get(c)
-}
-
 type TraceInfo struct {
 	// DNSLookup is a duration that transport took to perform
 	// DNS lookup.
@@ -56,6 +57,8 @@ type TraceInfo struct {
 	// RequestAttempt is to represent the request attempt made during a
 	// request execution flow, including retry count.
 	RequestAttempt int
+	// Hedged is whether the response was received by a hedged attempt.
+	Hedged bool
 	// RemoteAddr returns the remote network address.
 	RemoteAddr net.Addr
 }
@@ -70,59 +73,57 @@ type traceContext struct {
 	gotFirstResponseByte time.Time
 	endTime              time.Time
 	gotConnInfo          httptrace.GotConnInfo
+	hooks                *httptrace.ClientTrace
 }
 
 func (t *traceContext) createContext(ctx context.Context) context.Context {
-	return httptrace.WithClientTrace(
-		ctx,
-		&httptrace.ClientTrace{
-			DNSStart: func(_ httptrace.DNSStartInfo) {
-				t.dnsStart = time.Now()
-			},
-			DNSDone: func(_ httptrace.DNSDoneInfo) {
+	t.hooks = &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			t.dnsDone = time.Now()
+		},
+		ConnectStart: func(_, _ string) {
+			if t.dnsDone.IsZero() {
 				t.dnsDone = time.Now()
-			},
-			ConnectStart: func(_, _ string) {
-				if t.dnsDone.IsZero() {
-					t.dnsDone = time.Now()
-				}
-				if t.dnsStart.IsZero() {
-					t.dnsStart = t.dnsDone
-				}
-			},
-			ConnectDone: func(net, addr string, err error) {
-				t.connectDone = time.Now()
-			},
-			GetConn: func(_ string) {
-				t.getConn = time.Now()
-			},
-			GotConn: func(ci httptrace.GotConnInfo) {
-				t.gotConn = time.Now()
-				t.gotConnInfo = ci
-			},
-			GotFirstResponseByte: func() {
-				t.gotFirstResponseByte = time.Now()
-			},
-			TLSHandshakeStart: func() {
-				t.tlsHandshakeStart = time.Now()
-			},
-			TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
-				t.tlsHandshakeDone = time.Now()
-			},
+			}
+			if t.dnsStart.IsZero() {
+				t.dnsStart = t.dnsDone
+			}
+		},
+		ConnectDone: func(net, addr string, err error) {
+			t.connectDone = time.Now()
 		},
-	)
+		GetConn: func(_ string) {
+			t.getConn = time.Now()
+		},
+		GotConn: func(ci httptrace.GotConnInfo) {
+			t.gotConn = time.Now()
+			t.gotConnInfo = ci
+		},
+		GotFirstResponseByte: func() {
+			t.gotFirstResponseByte = time.Now()
+		},
+		TLSHandshakeStart: func() {
+			t.tlsHandshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			t.tlsHandshakeDone = time.Now()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, t.hooks)
 }
 
-func (t *traceContext) Get(client *Client) TraceInfo {
+func (t *traceContext) Get() TraceInfo {
 	ct := t
 	ti := TraceInfo{
-		DNSLookup:      ct.dnsDone.Sub(ct.dnsStart),
-		TLSHandshake:   ct.tlsHandshakeDone.Sub(ct.tlsHandshakeStart),
-		ServerTime:     ct.gotFirstResponseByte.Sub(ct.gotConn),
-		IsConnReused:   ct.gotConnInfo.Reused,
-		IsConnWasIdle:  ct.gotConnInfo.WasIdle,
-		ConnIdleTime:   ct.gotConnInfo.IdleTime,
-		RequestAttempt: client.attempt,
+		DNSLookup:     ct.dnsDone.Sub(ct.dnsStart),
+		TLSHandshake:  ct.tlsHandshakeDone.Sub(ct.tlsHandshakeStart),
+		ServerTime:    ct.gotFirstResponseByte.Sub(ct.gotConn),
+		IsConnReused:  ct.gotConnInfo.Reused,
+		IsConnWasIdle: ct.gotConnInfo.WasIdle,
+		ConnIdleTime:  ct.gotConnInfo.IdleTime,
 	}
 	// Calculate the total time accordingly,
 	// when connection is reused
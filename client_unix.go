@@ -0,0 +1,125 @@
+package requests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// HttpUnixSchemeName is the scheme of URLs served over a Unix domain socket,
+	// whose host is the escaped socket path, e.g. "http+unix://%2Fvar%2Frun%2Fdocker.sock/v1.41/info".
+	HttpUnixSchemeName = `http+unix`
+
+	unixSocketHost = `localhost`
+)
+
+// WithUnixSocket sends every request of the client over the Unix domain socket at path,
+// e.g. "/var/run/docker.sock", whatever the host of its URL.
+// The BaseUrl is set to "http://localhost" when it is empty, so URIs like "/v1.41/info" can be used.
+// The client gets a copy of its http.Client, so an http.Client shared with other code,
+// e.g. http.DefaultClient, keeps its transport.
+func (c *Client) WithUnixSocket(path string) *Client {
+	transport, ok := c.Transport.(*http.Transport)
+	if c.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		c.Logger.Errorf("WithUnixSocket failed: unsupported transport %T", c.Transport)
+		return c
+	}
+	// The transport is cloned as it may be shared, and its connections are to another host.
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return unixDialer().DialContext(ctx, "unix", path)
+	}
+	httpClient := *c.Client
+	httpClient.Transport = transport
+	c.Client = &httpClient
+	if c.BaseUrl == "" {
+		c.BaseUrl = HttpSchemeName + "://" + unixSocketHost
+	}
+	return c
+}
+
+func unixDialer() *net.Dialer {
+	return &net.Dialer{Timeout: 30 * time.Second}
+}
+
+// unixTransport sends the requests of http+unix URLs over the socket of their host.
+// Connections are pooled by host, so every socket has a pool of its own.
+type unixTransport struct {
+	transport *http.Transport
+}
+
+// escapeUnixHost escapes the percent signs of the escaped socket path in the host of an http+unix URL,
+// so the host of the parsed URL is the escaped socket path.
+//
+// url.Parse accepts "%25" and the escapes of non-ASCII bytes in a host, but rejects the escapes of
+// other ASCII bytes such as "%2F". Those are escaped once more, "%2F" becomes "%252F", which the
+// parsed host unescapes back to "%2F". The URL is returned as it is when it is not an http+unix URL,
+// and escaping an already escaped URL changes nothing.
+func escapeUnixHost(rawURL string) string {
+	prefix := HttpUnixSchemeName + "://"
+	if len(rawURL) < len(prefix) || !strings.EqualFold(rawURL[:len(prefix)], prefix) {
+		return rawURL
+	}
+	host := rawURL[len(prefix):]
+	rest := ""
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host, rest = host[:i], host[i:]
+	}
+	var b strings.Builder
+	for i := 0; i < len(host); i++ {
+		if host[i] == '%' && i+2 < len(host) {
+			c, err := strconv.ParseUint(host[i+1:i+3], 16, 8)
+			if err == nil && c < utf8.RuneSelf && c != '%' {
+				b.WriteString("%25")
+				continue
+			}
+		}
+		b.WriteByte(host[i])
+	}
+	return rawURL[:len(prefix)] + b.String() + rest
+}
+
+// unixSocketPath returns the socket path of the host of an http+unix URL.
+func unixSocketPath(host string) string {
+	if path, err := url.PathUnescape(host); err == nil {
+		return path
+	}
+	return host
+}
+
+// newUnixTransport returns a transport with the settings of transport dialing Unix domain sockets.
+func newUnixTransport(transport *http.Transport) *unixTransport {
+	transport = transport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		return unixDialer().DialContext(ctx, "unix", unixSocketPath(host))
+	}
+	return &unixTransport{transport: transport}
+}
+
+func (t *unixTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	r := request.Clone(request.Context())
+	r.URL.Scheme = HttpSchemeName
+	if r.Host == "" || r.Host == request.URL.Host {
+		r.Host = unixSocketHost
+	}
+	response, err := t.transport.RoundTrip(r)
+	if response != nil {
+		response.Request = request
+	}
+	return response, err
+}
@@ -0,0 +1,96 @@
+package requests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newUnixServer(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name+".sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name + " " + r.Host + " " + r.URL.RequestURI()))
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return path
+}
+
+func TestClientWithUnixSocket(t *testing.T) {
+	path := newUnixServer(t, "docker")
+	var hooked bool
+	client := New().WithUnixSocket(path).Use(func(c *Client, r *http.Request) (*Response, error) {
+		hooked = true
+		return c.Next(r)
+	})
+	body, err := client.GetBytes(context.Background(), "/v1.41/info", url.Values{"all": {"1"}})
+	require.NoError(t, err)
+	require.Equal(t, "docker localhost /v1.41/info?all=1", string(body))
+	require.True(t, hooked)
+
+	// The shared http.Client keeps its transport.
+	transport := http.DefaultClient.Transport
+	client = NewWithHttpClient(http.DefaultClient).WithUnixSocket(path)
+	require.Equal(t, transport, http.DefaultClient.Transport)
+	require.NotSame(t, http.DefaultClient, client.Client)
+	body, err = client.GetBytes(context.Background(), "/info", nil)
+	require.NoError(t, err)
+	require.Equal(t, "docker localhost /info", string(body))
+
+	client = New().SetBaseURL("http://docker/v1.41/").WithUnixSocket(path)
+	body, err = client.GetBytes(context.Background(), "containers/json", nil)
+	require.NoError(t, err)
+	require.Equal(t, "docker docker /v1.41/containers/json", string(body))
+}
+
+func TestClientHttpUnixScheme(t *testing.T) {
+	a, b := newUnixServer(t, "a"), newUnixServer(t, "b")
+	client := New()
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{a, "a localhost /info"},
+		{b, "b localhost /info"},
+		{a, "a localhost /info"},
+	} {
+		body, err := client.GetBytes(context.Background(), "http+unix://"+url.PathEscape(tt.path)+"/info", nil)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, string(body))
+	}
+
+	body, err := client.SetBaseURL("http+unix://"+url.PathEscape(b)+"/v1/").GetBytes(context.Background(), "info", nil)
+	require.NoError(t, err)
+	require.Equal(t, "b localhost /v1/info", string(body))
+}
+
+func TestEscapeUnixHost(t *testing.T) {
+	u, err := ParseURIQuery("http+unix://%2Ftmp%2Fa%C3%A9.sock/x?a=1")
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/aé.sock", unixSocketPath(u.Host))
+	require.Equal(t, "/x", u.Path)
+	again, err := ParseURIQuery(u.String())
+	require.NoError(t, err)
+	require.Equal(t, u.Host, again.Host)
+	require.Equal(t, "https://a%2Fb/", escapeUnixHost("https://a%2Fb/"))
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{"http+unix://%2Ftmp%2Fa.sock/x", "http+unix://%252Ftmp%252Fa.sock/x"},
+		{"http+unix://%252Ftmp%252Fa.sock", "http+unix://%252Ftmp%252Fa.sock"},
+		{"http+unix://a%C3%A9%7e%2?q=%2F", "http+unix://a%C3%A9%257e%2?q=%2F"},
+		{"http+unix://%zz", "http+unix://%zz"},
+	} {
+		require.Equal(t, tt.want, escapeUnixHost(tt.input), tt.input)
+	}
+}
@@ -0,0 +1,105 @@
+package requests
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	HttpsSchemeName = `https`
+)
+
+// schemeRegexp matches URIs with an explicit scheme, e.g. "https://" or "http+unix://".
+var schemeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.\-]*://`)
+
+// SetBaseURLJoin joins the relative request URIs to the path of the base URL, as earlier versions did,
+// instead of resolving them as RFC 3986 references: "/users" against "https://api.example.com/v1"
+// is then "https://api.example.com/v1/users" rather than "https://api.example.com/users".
+func (c *Client) SetBaseURLJoin(join bool) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.baseURLJoin = join
+	return c
+}
+
+// SetDefaultScheme sets the scheme of request URIs and base URLs which have none, https by default.
+func (c *Client) SetDefaultScheme(scheme string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.defaultScheme = strings.TrimSuffix(scheme, "://")
+	return c
+}
+
+// ParseURIQuery parses uri and sets the values of query in its query string,
+// they replace the values of the same keys in uri, see QueryMergeReplace.
+func ParseURIQuery(uri string, query ...url.Values) (*url.URL, error) {
+	u, err := parseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+	setQuery(u, QueryMergeReplace, query...)
+	return u, nil
+}
+
+func setQuery(u *url.URL, mode QueryMergeMode, query ...url.Values) {
+	for _, value := range query {
+		u.RawQuery = mergeRawQuery(u.RawQuery, mode, value)
+	}
+}
+
+// parseURL parses rawURL, accepting the escaped socket path in the host of http+unix URLs.
+func parseURL(rawURL string) (*url.URL, error) {
+	return url.Parse(escapeUnixHost(rawURL))
+}
+
+// resolveURL resolves uri against baseUrl.
+//
+// URIs with a scheme are used as they are. Other URIs are resolved as RFC 3986 references against baseUrl:
+// with the base "https://api.example.com/v1/", "users" resolves to "https://api.example.com/v1/users",
+// "/users" to "https://api.example.com/users", "../v2/users" to "https://api.example.com/v2/users"
+// and "//cdn.example.com/a" to "https://cdn.example.com/a". When join is set, the path of baseUrl is
+// treated as a directory and the URIs which are not network-path references are relative to it,
+// so "/users" against "https://api.example.com/v1" is "https://api.example.com/v1/users".
+// The default scheme is added to URIs and base URLs without one.
+func resolveURL(baseUrl, uri, scheme string, join bool) (*url.URL, error) {
+	if scheme == "" {
+		scheme = HttpsSchemeName
+	}
+	if schemeRegexp.MatchString(uri) {
+		return parseURL(uri)
+	}
+	if baseUrl == "" {
+		return url.Parse(scheme + "://" + strings.TrimPrefix(uri, "//"))
+	}
+	if !schemeRegexp.MatchString(baseUrl) {
+		baseUrl = scheme + "://" + strings.TrimPrefix(baseUrl, "//")
+	}
+	base, err := parseURL(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	if uri == "" {
+		return base, nil
+	}
+	if join && !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		if base.RawPath != "" {
+			base.RawPath += "/"
+		}
+	}
+	if !strings.HasPrefix(uri, "//") {
+		if join {
+			uri = strings.TrimLeft(uri, "/")
+		}
+		if i := strings.IndexAny(uri, ":/?#"); i >= 0 && uri[i] == ':' {
+			// A colon in the first segment would be parsed as a scheme.
+			uri = "./" + uri
+		}
+	}
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	return base.ResolveReference(ref), nil
+}
@@ -0,0 +1,44 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveURL(t *testing.T) {
+	for _, test := range []struct {
+		base    string
+		uri     string
+		scheme  string
+		join    bool
+		expect  string
+		wantErr bool
+	}{
+		{"https://api.example.com/v1/", "users?page=2", "", false, "https://api.example.com/v1/users?page=2", false},
+		{"https://api.example.com/v1", "users", "", false, "https://api.example.com/users", false},
+		{"https://api.example.com/v1", "/users", "", false, "https://api.example.com/users", false},
+		{"https://api.example.com/v1/a", "../v2/users", "", false, "https://api.example.com/v2/users", false},
+		{"https://api.example.com/v1", "//cdn.example.com/a", "", false, "https://cdn.example.com/a", false},
+		{"https://api.example.com/v1", "http://other.example.com/x", "", false, "http://other.example.com/x", false},
+		{"https://api.example.com/v1", "", "", false, "https://api.example.com/v1", false},
+		{"https://api.example.com", "users:search", "", false, "https://api.example.com/users:search", false},
+		{"https://api.example.com/v1", "/users", "", true, "https://api.example.com/v1/users", false},
+		{"https://api.example.com/v1", "/v1/http-status", "", true, "https://api.example.com/v1/v1/http-status", false},
+		{"https://api.example.com/v1", "../v2/users", "", true, "https://api.example.com/v2/users", false},
+		{"https://api.example.com/v1", "//cdn.example.com/a", "", true, "https://cdn.example.com/a", false},
+		{"api.example.com", "/users", "http", false, "http://api.example.com/users", false},
+		{"", "localhost:8080/http-status", "", false, "https://localhost:8080/http-status", false},
+		{"", "127.0.0.1/x", "http", false, "http://127.0.0.1/x", false},
+		{"https://api.example.com", "/users/%zz", "", false, "", true},
+		{"https://api example.com", "/users", "", false, "", true},
+	} {
+		u, err := resolveURL(test.base, test.uri, test.scheme, test.join)
+		if test.wantErr {
+			require.Error(t, err, test.uri)
+			continue
+		}
+		require.NoError(t, err, test.uri)
+		require.Equal(t, test.expect, u.String())
+	}
+}
@@ -0,0 +1,187 @@
+package requests
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	HttpHeaderContentTypeText = `text/plain`
+)
+
+var (
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// Codec encodes request bodies and decodes response bodies of a media type.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// RegisterCodec registers codec for mediaType, e.g. "application/msgpack".
+//
+// Media types with a structured syntax suffix, e.g. "application/problem+json",
+// use the codec of "application/json" unless they are registered themselves.
+func (c *Client) RegisterCodec(mediaType string, codec Codec) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.codecs == nil {
+		c.codecs = make(map[string]Codec)
+	}
+	c.codecs[strings.ToLower(mediaType)] = codec
+	return c
+}
+
+// Codec returns the codec of contentType.
+func (c *Client) Codec(contentType string) (Codec, error) {
+	mediaType := parseMediaType(contentType)
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if codec, ok := c.codecs[mediaType]; ok {
+		return codec, nil
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		if codec, ok := c.codecs["application/"+mediaType[i+1:]]; ok {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf(`%w "%s"`, ErrUnsupportedContentType, contentType)
+}
+
+// ForceContentType decodes every response as contentType, whatever its Content-Type header,
+// for servers which send a wrong one. An empty contentType uses the header again.
+func (c *Client) ForceContentType(contentType string) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.forceContentType = contentType
+	return c
+}
+
+// ForceContentType decodes the response of this request as contentType.
+func (r *ClientRequest) ForceContentType(contentType string) *ClientRequest {
+	r.forceContentType = contentType
+	return r
+}
+
+func (c *Client) registerDefaultCodecs() {
+	c.codecs = make(map[string]Codec)
+	json, xml := jsonCodec{client: c}, xmlCodec{client: c}
+	c.codecs[HttpHeaderContentTypeJson] = json
+	c.codecs["text/json"] = json
+	c.codecs[HttpHeaderContentTypeXml] = xml
+	c.codecs["text/xml"] = xml
+	c.codecs[HttpHeaderContentTypeForm] = formCodec{client: c}
+	c.codecs[HttpHeaderContentTypeText] = TextCodec{}
+}
+
+func parseMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// jsonCodec uses the JSON functions of the client, see SetJSONMarshaler.
+type jsonCodec struct {
+	client *Client
+}
+
+func (j jsonCodec) Marshal(v any) ([]byte, error) {
+	return j.client.JSONMarshal(v)
+}
+
+func (j jsonCodec) Unmarshal(data []byte, v any) error {
+	return j.client.JSONUnmarshal(data, v)
+}
+
+// xmlCodec uses the XML functions of the client, see SetXMLMarshaler.
+type xmlCodec struct {
+	client *Client
+}
+
+func (x xmlCodec) Marshal(v any) ([]byte, error) {
+	return x.client.XMLMarshal(v)
+}
+
+func (x xmlCodec) Unmarshal(data []byte, v any) error {
+	return x.client.XMLUnmarshal(data, v)
+}
+
+// formCodec encodes url.Values, maps and structs with the query encoder of the client,
+// and decodes into *url.Values, *map[string][]string or *map[string]string.
+type formCodec struct {
+	client *Client
+}
+
+func (f formCodec) Marshal(v any) ([]byte, error) {
+	switch val := v.(type) {
+	case url.Values:
+		return []byte(val.Encode()), nil
+	case *url.Values:
+		return []byte(val.Encode()), nil
+	}
+	f.client.lock.RLock()
+	encoder := f.client.queryEncoder
+	f.client.lock.RUnlock()
+	if encoder == nil {
+		encoder = NewQueryEncoder()
+	}
+	query, err := encoder.Encode(v)
+	return []byte(query), err
+}
+
+func (f formCodec) Unmarshal(data []byte, v any) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch d := v.(type) {
+	case *url.Values:
+		*d = values
+	case *map[string][]string:
+		*d = values
+	case *map[string]string:
+		*d = make(map[string]string, len(values))
+		for k := range values {
+			(*d)[k] = values.Get(k)
+		}
+	default:
+		return fmt.Errorf(`%w: cannot decode form into %T`, ErrUnsupportedContentType, v)
+	}
+	return nil
+}
+
+// TextCodec encodes values as text and decodes into *string, *[]byte or an encoding.TextUnmarshaler.
+type TextCodec struct{}
+
+func (TextCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		return m.MarshalText()
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		return []byte(s.String()), nil
+	}
+	return []byte(AnyString(v)), nil
+}
+
+func (TextCodec) Unmarshal(data []byte, v any) error {
+	switch d := v.(type) {
+	case *string:
+		*d = string(data)
+	case *[]byte:
+		*d = append((*d)[:0], data...)
+	case encoding.TextUnmarshaler:
+		return d.UnmarshalText(data)
+	default:
+		return fmt.Errorf(`%w: cannot decode text into %T`, ErrUnsupportedContentType, v)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package requests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCodec(t *testing.T) {
+	client := New()
+	for _, test := range []struct {
+		contentType string
+		expect      Codec
+	}{
+		{"application/json", jsonCodec{client: client}},
+		{"application/problem+json; charset=utf-8", jsonCodec{client: client}},
+		{"Text/XML", xmlCodec{client: client}},
+		{"application/atom+xml", xmlCodec{client: client}},
+		{"application/x-www-form-urlencoded", formCodec{client: client}},
+		{"text/plain; charset=utf-8", TextCodec{}},
+		{"application/octet-stream", nil},
+		{"", nil},
+	} {
+		codec, err := client.Codec(test.contentType)
+		if test.expect == nil {
+			require.ErrorIs(t, err, ErrUnsupportedContentType, test.contentType)
+			continue
+		}
+		require.NoError(t, err, test.contentType)
+		require.Equal(t, test.expect, codec, test.contentType)
+	}
+}
+
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	return []byte("UPPER"), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v any) error {
+	*(v.(*string)) = "upper:" + string(data)
+	return nil
+}
+
+func TestResponseUnmarshalCodec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set(HttpHeaderContentType, r.URL.Query().Get("type"))
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+	uri := func(contentType string) string {
+		return server.URL + "?" + url.Values{"type": {contentType}}.Encode()
+	}
+	client := New().RegisterCodec("application/x-upper", upperCodec{})
+
+	var form url.Values
+	err := client.NewRequest(context.Background(), http.MethodPost, uri(HttpHeaderContentTypeForm), queryAddress{City: "Paris"}).
+		AsForm().
+		DoUnmarshal(&form)
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"city": {"Paris"}}, form)
+
+	var text string
+	require.NoError(t, client.PostUnmarshal(context.Background(), uri("application/x-upper"), "body", &text))
+	require.Equal(t, "upper:body", text)
+
+	var m map[string]string
+	err = client.PostUnmarshal(context.Background(), uri("application/octet-stream"), `{"k":"v"}`, &m)
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	err = client.NewRequest(context.Background(), http.MethodPost, uri("text/html"), `{"k":"v"}`).
+		ForceContentType(HttpHeaderContentTypeJson).
+		DoUnmarshal(&m)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"k": "v"}, m)
+
+	err = client.NewRequest(context.Background(), http.MethodPost, uri("application/x-upper"), struct{}{}).
+		WithContentType("application/x-upper").
+		DoUnmarshal(&text)
+	require.NoError(t, err)
+	require.Equal(t, "upper:UPPER", text)
+}
+
+func TestResponseUnmarshalEmpty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-content":
+			w.Header().Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+			w.WriteHeader(http.StatusNoContent)
+		case "/untyped":
+			// A nil Content-Type stops net/http from sniffing one.
+			w.Header()[HttpHeaderContentType] = nil
+			_, _ = w.Write([]byte(`{"k":"v"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New()
+	for _, path := range []string{"/no-content", "/untyped"} {
+		m := map[string]string{"kept": "1"}
+		require.NoError(t, client.GetUnmarshal(context.Background(), server.URL+path, nil, &m), path)
+		require.Equal(t, map[string]string{"kept": "1"}, m, path)
+	}
+	var m map[string]string
+	require.NoError(t, client.Unmarshal("application/octet-stream", nil, &m))
+	require.ErrorIs(t, client.Unmarshal("application/octet-stream", []byte("x"), &m), ErrUnsupportedContentType)
+}
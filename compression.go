@@ -0,0 +1,196 @@
+package requests
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	HttpHeaderContentEncoding = `Content-Encoding`
+
+	defaultCompressionMinSize = 1024
+)
+
+// DefaultExemptContentTypes are the content types which are already compressed,
+// a type ending with "/" matches every subtype.
+var DefaultExemptContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-rar-compressed",
+	"application/x-xz",
+	"application/zstd",
+}
+
+// Compressor compresses request bodies for a Content-Encoding.
+type Compressor interface {
+	// Encoding is the value of the Content-Encoding header, e.g. "gzip".
+	Encoding() string
+	// Compress returns a writer which compresses to w, it is closed once the body is written.
+	Compress(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor compresses with gzip, Level is a compress/gzip level, the default when zero.
+type GzipCompressor struct {
+	Level int
+}
+
+func (g GzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+func (g GzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if g.Level == 0 {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, g.Level)
+}
+
+// DeflateCompressor compresses with deflate, Level is a compress/flate level, the default when zero.
+type DeflateCompressor struct {
+	Level int
+}
+
+func (d DeflateCompressor) Encoding() string {
+	return "deflate"
+}
+
+func (d DeflateCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	level := d.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	return flate.NewWriter(w, level)
+}
+
+// Compression compresses request bodies and sets their Content-Encoding.
+//
+//	client.SetCompression(&requests.Compression{MinSize: 4096})
+//
+// Bodies with a Content-Encoding are sent as they are.
+type Compression struct {
+	// Compressor is the encoding of the bodies, gzip when nil.
+	Compressor Compressor
+	// MinSize is the minimum size of a compressed body, 1024 bytes when zero,
+	// bodies of an unknown size are always compressed.
+	MinSize int64
+	// ExemptContentTypes are never compressed, DefaultExemptContentTypes when nil.
+	ExemptContentTypes []string
+}
+
+// SetCompression enables compression of request bodies, a nil compression disables it.
+func (c *Client) SetCompression(compression *Compression) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.compression = compression
+	return c
+}
+
+// SetCompression overrides the client compression for this request.
+func (r *ClientRequest) SetCompression(compression *Compression) *ClientRequest {
+	r.compression = compression
+	return r
+}
+
+func (cp *Compression) compressor() Compressor {
+	if cp.Compressor != nil {
+		return cp.Compressor
+	}
+	return GzipCompressor{}
+}
+
+func (cp *Compression) compresses(request *http.Request) bool {
+	if !hasBody(request) || request.Header.Get(HttpHeaderContentEncoding) != "" {
+		return false
+	}
+	minSize := cp.MinSize
+	if minSize == 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if request.ContentLength >= 0 && request.ContentLength < minSize {
+		return false
+	}
+	exempt := cp.ExemptContentTypes
+	if exempt == nil {
+		exempt = DefaultExemptContentTypes
+	}
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get(HttpHeaderContentType))
+	for _, t := range exempt {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply compresses the body of request, in memory when it is buffered
+// and while it is sent when it is streamed.
+func (cp *Compression) apply(request *http.Request, stream bool) error {
+	if !cp.compresses(request) {
+		return nil
+	}
+	compressor := cp.compressor()
+	if stream {
+		getBody := request.GetBody
+		request.Body, request.ContentLength, request.GetBody = compressReader(compressor, request.Body), -1, nil
+		if getBody != nil {
+			request.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return compressReader(compressor, body), nil
+			}
+		}
+	} else {
+		var buf bytes.Buffer
+		w, err := compressor.Compress(&buf)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, request.Body)
+		_ = request.Body.Close()
+		if err != nil {
+			return err
+		}
+		if err = w.Close(); err != nil {
+			return err
+		}
+		if err = setRequestBody(request, &buf); err != nil {
+			return err
+		}
+	}
+	request.Header.Set(HttpHeaderContentEncoding, compressor.Encoding())
+	return nil
+}
+
+// compressReader returns body compressed while it is read.
+func compressReader(compressor Compressor, body io.ReadCloser) io.ReadCloser {
+	return &pipeReadCloser{
+		source: body,
+		write: func(pw *io.PipeWriter) {
+			defer body.Close()
+			w, err := compressor.Compress(pw)
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if _, err = io.Copy(w, body); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			_ = pw.CloseWithError(w.Close())
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package requests
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		switch r.Header.Get(HttpHeaderContentEncoding) {
+		case "gzip":
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		case "deflate":
+			body = flate.NewReader(r.Body)
+		}
+		b, err := io.ReadAll(body)
+		require.NoError(t, err)
+		if r.Header.Get("X-Fail") != "" && atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Encoding", r.Header.Get(HttpHeaderContentEncoding))
+		w.Header().Set("X-Length", AnyString(r.ContentLength))
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	large := `{"items":"` + strings.Repeat("a", 4096) + `"}`
+	client := New().SetCompression(&Compression{}).SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus))
+	tests := []struct {
+		name     string
+		request  *ClientRequest
+		body     string
+		encoding string
+		chunked  bool
+	}{
+		{
+			name:    "small",
+			request: client.NewRequest(context.Background(), http.MethodPost, server.URL, `{"k":"v"}`),
+			body:    `{"k":"v"}`,
+		},
+		{
+			name:     "buffered",
+			request:  client.NewRequest(context.Background(), http.MethodPost, server.URL, large).AsJson(),
+			body:     large,
+			encoding: "gzip",
+		},
+		{
+			name: "stream",
+			request: client.NewRequest(context.Background(), http.MethodPut, server.URL, strings.NewReader(large)).
+				SetCompression(&Compression{Compressor: DeflateCompressor{}}).
+				WithHeader("X-Fail", "1"),
+			body:     large,
+			encoding: "deflate",
+			chunked:  true,
+		},
+		{
+			name:    "exempt",
+			request: client.NewRequest(context.Background(), http.MethodPut, server.URL, large).WithContentType("image/png"),
+			body:    large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.request.Do()
+			require.NoError(t, err)
+			defer response.Close()
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.Equal(t, tt.body, response.ReadAllString())
+			require.Equal(t, tt.encoding, response.Header.Get("X-Encoding"))
+			require.Equal(t, tt.chunked, response.Header.Get("X-Length") == "-1")
+		})
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
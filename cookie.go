@@ -1,6 +1,8 @@
 package requests
 
 import (
+	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -43,13 +45,38 @@ func (v Cookie) Has(key string) bool {
 	_, ok := v[key]
 	return ok
 }
+func (v Cookie) Clone() Cookie {
+	clone := make(Cookie, len(v))
+	for k, vs := range v {
+		clone[k] = append([]string(nil), vs...)
+	}
+	return clone
+}
+
+// Encode encodes the cookies sorted by name.
 func (v Cookie) Encode() string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	cookieStr := ""
-	for s := range v {
+	for _, s := range keys {
 		if cookieStr != "" {
-			cookieStr += ";"
+			cookieStr += "; "
 		}
 		cookieStr += s + "=" + v.Get(s)
 	}
 	return cookieStr
 }
+
+// addCookieHeader adds cookie to the Cookie header of header,
+// after the cookies which are already in it.
+func addCookieHeader(header http.Header, cookie Cookie) {
+	if encoded := cookie.Encode(); encoded != "" {
+		if existing := header.Get(HttpHeaderCookie); existing != "" {
+			encoded = existing + "; " + encoded
+		}
+		header.Set(HttpHeaderCookie, encoded)
+	}
+}
@@ -1,6 +1,7 @@
 package requests
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -8,6 +9,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/url"
 	"os"
@@ -32,6 +34,25 @@ func Sha256(str string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// Hmac returns the HMAC of data with key and the hash function h, e.g. sha256.New.
+func Hmac(h func() hash.Hash, key, data []byte) []byte {
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func HmacMd5(key, str string) string {
+	return hex.EncodeToString(Hmac(md5.New, []byte(key), []byte(str)))
+}
+
+func HmacSha1(key, str string) string {
+	return hex.EncodeToString(Hmac(sha1.New, []byte(key), []byte(str)))
+}
+
+func HmacSha256(key, str string) string {
+	return hex.EncodeToString(Hmac(sha256.New, []byte(key), []byte(str)))
+}
+
 func URLEncode(str string) string {
 	return url.QueryEscape(str)
 }
@@ -286,3 +286,23 @@ func TestBase64StdEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestHmac(t *testing.T) {
+	const key, str = "key", "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "md5", got: HmacMd5(key, str), want: "80070713463e7749b90c2dc24911e275"},
+		{name: "sha1", got: HmacSha1(key, str), want: "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"},
+		{name: "sha256", got: HmacSha256(key, str), want: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Hmac() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -38,6 +38,8 @@ type Downloader struct {
 	FileName string
 	//协程数量
 	CoroutineNumber int
+	// 下载进度，默认使用Client的SetDownloadProgress
+	Progress ProgressFunc
 
 	userAgent string
 	ctx       context.Context
@@ -45,6 +47,8 @@ type Downloader struct {
 	fileSize int
 	// 已完成文件切片
 	doneFilePart []downloaderPart
+	// 整个文件的下载进度
+	tracker *progressTracker
 }
 
 func (c *Client) Download(ctx context.Context, uri, fileName string) error {
@@ -56,8 +60,12 @@ func NewDownloader(req *Client, ctx context.Context, url, fileName string, corou
 	if len(coroutineNumbers) > 0 {
 		coroutineNumber = coroutineNumbers[0]
 	}
+	req.lock.RLock()
+	progress := req.downloadProgress
+	req.lock.RUnlock()
 	return &Downloader{
 		req:             req,
+		Progress:        progress,
 		userAgent:       RandomUserAgent(),
 		ctx:             ctx,
 		fileSize:        0,
@@ -88,6 +96,9 @@ func (d *Downloader) Download() error {
 	if err != nil {
 		return err
 	}
+	if d.Progress != nil {
+		d.tracker = newProgressTracker(d.Progress, false, int64(d.fileSize))
+	}
 	// 检查是否支持断点续传
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Accept-Ranges
 	if resp.Header.Get(HttpHeaderAcceptRanges) != "bytes" {
@@ -97,14 +108,31 @@ func (d *Downloader) Download() error {
 }
 
 func (d *Downloader) newReq(method string) (req *http.Request, err error) {
-	req, err = http.NewRequest(method, d.URL, nil)
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err = http.NewRequestWithContext(ctx, method, d.URL, nil)
 	if err != nil {
 		return
 	}
 	req.Header.Set(HttpHeaderUserAgent, d.userAgent)
+	// 下载进度按整个文件统计，不按单个请求统计
+	r := d.req.NewRequest(ctx, method, d.URL, nil)
+	r.downloadProgress = nil
+	req = req.WithContext(context.WithValue(ctx, requestContextKey, r))
+	r.RawRequest = req
 	return
 }
 
+// body wraps the body of a download response to report the progress of the whole file.
+func (d *Downloader) body(body io.ReadCloser, part bool) io.ReadCloser {
+	if d.tracker == nil {
+		return body
+	}
+	return &progressReadCloser{ReadCloser: body, tracker: d.tracker, part: part}
+}
+
 func (d *Downloader) simpleDownload() error {
 	r, err := d.newReq(http.MethodGet)
 	if err != nil {
@@ -118,7 +146,7 @@ func (d *Downloader) simpleDownload() error {
 		return fmt.Errorf("incorrect response status code %v", resp.StatusCode)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(d.body(resp.Body, false))
 	if err != nil {
 		return err
 	}
@@ -191,9 +219,6 @@ func (d *Downloader) downloadPart(c downloaderPart) error {
 	if err != nil {
 		return err
 	}
-	if d.ctx != nil {
-		r.WithContext(d.ctx)
-	}
 	r.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", c.From, c.To))
 	resp, err := d.req.callRequest(r)
 	if err != nil {
@@ -203,7 +228,7 @@ func (d *Downloader) downloadPart(c downloaderPart) error {
 		return fmt.Errorf("incorrect response status code %v", resp.StatusCode)
 	}
 	defer resp.Body.Close()
-	bs, err := io.ReadAll(resp.Body)
+	bs, err := io.ReadAll(d.body(resp.Body, true))
 	if err != nil {
 		return err
 	}
@@ -11,6 +11,7 @@ type (
 	ResponseCallback func(client *Client, request *http.Request, response *Response) error
 	ErrorHook        func(client *Client, request *http.Request, err error)
 	SuccessHook      func(client *Client, response *Response)
+	AttemptHook      func(client *Client, request *http.Request) (done func(response *Response, err error), err error)
 
 	CtxKeyString string
 )
@@ -0,0 +1,259 @@
+package requests
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	bulkheadPriorityKey CtxKeyString = "__bulkhead_priority_key"
+)
+
+var (
+	ErrBulkheadFull    = errors.New("bulkhead queue is full")
+	ErrBulkheadTimeout = errors.New("bulkhead queue wait timed out")
+)
+
+// BulkheadStats is a snapshot of one bulkhead compartment.
+type BulkheadStats struct {
+	// Active is the number of requests in flight.
+	Active int
+	// Queued is the number of requests waiting for a slot.
+	Queued int
+}
+
+// Bulkhead caps the number of requests in flight per key, the request host by default.
+//
+// Requests above MaxConcurrent wait in a queue ordered by priority, see WithPriority,
+// and then by arrival. A slot is held until the response body is read to the end or closed.
+//
+//	bulkhead := requests.NewBulkhead(20)
+//	bulkhead.MaxQueue = 100
+//	bulkhead.MaxWait = time.Second
+//	client.WithBulkhead(bulkhead)
+type Bulkhead struct {
+	// MaxConcurrent is the maximum number of requests in flight per key.
+	MaxConcurrent int
+	// MaxQueue is the maximum number of requests waiting per key, zero means unbounded.
+	MaxQueue int
+	// MaxWait is the maximum time a request waits for a slot, zero only honors the request context.
+	MaxWait time.Duration
+	// KeyFunc returns the compartment key of a request, the host by default.
+	KeyFunc func(request *http.Request) string
+
+	mu           sync.Mutex
+	compartments map[string]*compartment
+}
+
+type compartment struct {
+	active int
+	queue  bulkheadQueue
+	seq    uint64
+}
+
+type bulkheadWaiter struct {
+	priority int
+	seq      uint64
+	index    int
+	granted  bool
+	ready    chan struct{}
+}
+
+// bulkheadQueue implements heap.Interface, higher priorities first and then FIFO.
+type bulkheadQueue []*bulkheadWaiter
+
+func (q bulkheadQueue) Len() int { return len(q) }
+func (q bulkheadQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q bulkheadQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *bulkheadQueue) Push(x any) {
+	w := x.(*bulkheadWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+func (q *bulkheadQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return w
+}
+
+// NewBulkhead creates a Bulkhead which allows maxConcurrent requests in flight per host.
+func NewBulkhead(maxConcurrent int) *Bulkhead {
+	return &Bulkhead{MaxConcurrent: maxConcurrent}
+}
+
+// WithPriority returns a context which queues its request with priority in a Bulkhead,
+// higher priorities are served first, the default is zero.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, bulkheadPriorityKey, priority)
+}
+
+// WithBulkhead adds the bulkhead to the client middlewares.
+func (c *Client) WithBulkhead(bulkhead *Bulkhead) *Client {
+	return c.Use(bulkhead.Middleware())
+}
+
+// Middleware returns the MiddlewareFunc of the bulkhead.
+func (b *Bulkhead) Middleware() MiddlewareFunc {
+	return func(c *Client, r *http.Request) (*Response, error) {
+		key := b.key(r)
+		if err := b.acquire(r.Context(), key); err != nil {
+			return nil, err
+		}
+		var once sync.Once
+		release := func() {
+			once.Do(func() {
+				b.release(key)
+			})
+		}
+		response, err := c.Next(r)
+		if err != nil || response == nil || response.Response == nil || response.Body == nil {
+			release()
+			return response, err
+		}
+		response.Body = &releaseReadCloser{ReadCloser: response.Body, release: release}
+		return response, err
+	}
+}
+
+// Stats returns the stats of the compartment of key.
+func (b *Bulkhead) Stats(key string) BulkheadStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cp, ok := b.compartments[key]; ok {
+		return BulkheadStats{Active: cp.active, Queued: len(cp.queue)}
+	}
+	return BulkheadStats{}
+}
+
+// AllStats returns the stats of every compartment with requests in flight or waiting.
+func (b *Bulkhead) AllStats() map[string]BulkheadStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := make(map[string]BulkheadStats, len(b.compartments))
+	for key, cp := range b.compartments {
+		stats[key] = BulkheadStats{Active: cp.active, Queued: len(cp.queue)}
+	}
+	return stats
+}
+
+func (b *Bulkhead) key(r *http.Request) string {
+	if b.KeyFunc != nil {
+		return b.KeyFunc(r)
+	}
+	return r.URL.Host
+}
+
+func (b *Bulkhead) maxConcurrent() int {
+	if b.MaxConcurrent > 0 {
+		return b.MaxConcurrent
+	}
+	return 1
+}
+
+func (b *Bulkhead) acquire(ctx context.Context, key string) error {
+	b.mu.Lock()
+	if b.compartments == nil {
+		b.compartments = make(map[string]*compartment)
+	}
+	cp, ok := b.compartments[key]
+	if !ok {
+		cp = &compartment{}
+		b.compartments[key] = cp
+	}
+	if cp.active < b.maxConcurrent() && len(cp.queue) == 0 {
+		cp.active++
+		b.mu.Unlock()
+		return nil
+	}
+	if b.MaxQueue > 0 && len(cp.queue) >= b.MaxQueue {
+		b.mu.Unlock()
+		return ErrBulkheadFull
+	}
+	priority, _ := ctx.Value(bulkheadPriorityKey).(int)
+	w := &bulkheadWaiter{priority: priority, seq: cp.seq, ready: make(chan struct{})}
+	cp.seq++
+	heap.Push(&cp.queue, w)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if b.MaxWait > 0 {
+		timer := time.NewTimer(b.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrBulkheadTimeout
+	}
+	b.mu.Lock()
+	if w.granted {
+		// The slot was handed over while giving up, pass it on.
+		b.mu.Unlock()
+		b.release(key)
+		return err
+	}
+	heap.Remove(&cp.queue, w.index)
+	b.mu.Unlock()
+	return err
+}
+
+func (b *Bulkhead) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cp, ok := b.compartments[key]
+	if !ok {
+		return
+	}
+	if len(cp.queue) > 0 {
+		w := heap.Pop(&cp.queue).(*bulkheadWaiter)
+		w.granted = true
+		close(w.ready)
+		return
+	}
+	cp.active--
+	if cp.active <= 0 {
+		delete(b.compartments, key)
+	}
+}
+
+// releaseReadCloser releases a bulkhead slot once the response body is read to the end or closed,
+// a body which is read and then replaced, e.g. by the debug callbacks, frees its slot too.
+type releaseReadCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseReadCloser) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releaseReadCloser) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,120 @@
+package requests
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkheadPerHost(t *testing.T) {
+	var active, peak int32
+	unblock := make(chan struct{})
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-unblock
+	}))
+	defer blocked.Close()
+	free := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer free.Close()
+	u, _ := url.Parse(blocked.URL)
+
+	bulkhead := NewBulkhead(2)
+	client := New().SetRetry(0, 0).WithBulkhead(bulkhead)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := client.Get(context.Background(), blocked.URL, nil)
+			require.NoError(t, err)
+			_ = response.Close()
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return bulkhead.Stats(u.Host) == BulkheadStats{Active: 2, Queued: 2}
+	}, time.Second, time.Millisecond)
+
+	// Another host has its own compartment.
+	response, err := client.Get(context.Background(), free.URL, nil)
+	require.NoError(t, err)
+	_ = response.Close()
+
+	close(unblock)
+	wg.Wait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+	require.Empty(t, bulkhead.AllStats())
+}
+
+func TestBulkheadLimits(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	bulkhead := &Bulkhead{MaxConcurrent: 1, MaxQueue: 1, MaxWait: 50 * time.Millisecond}
+	client := New().SetRetry(0, 0).WithBulkhead(bulkhead)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		response, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		_ = response.Close()
+	}()
+	require.Eventually(t, func() bool { return bulkhead.Stats(u.Host).Active == 1 }, time.Second, time.Millisecond)
+
+	timedOut := make(chan error)
+	go func() {
+		_, err := client.Get(context.Background(), server.URL, nil)
+		timedOut <- err
+	}()
+	require.Eventually(t, func() bool { return bulkhead.Stats(u.Host).Queued == 1 }, time.Second, time.Millisecond)
+	_, err := client.Get(context.Background(), server.URL, nil)
+	require.ErrorIs(t, err, ErrBulkheadFull)
+	require.ErrorIs(t, <-timedOut, ErrBulkheadTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Get(ctx, server.URL, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(unblock)
+	<-done
+	require.Equal(t, BulkheadStats{}, bulkhead.Stats(u.Host))
+}
+
+func TestBulkheadReleasesReadBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	bulkhead := &Bulkhead{MaxConcurrent: 1, MaxWait: time.Second}
+	// The debug callbacks read and replace the response body.
+	client := New().SetRetry(0, 0).EnableDebug().SetWriter(io.Discard).WithBulkhead(bulkhead)
+	client.SetLogger(NewLogger(log.New(io.Discard, "", 0), ""))
+	for i := 0; i < 3; i++ {
+		response, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, "ok", response.ReadAllString())
+	}
+	require.Equal(t, BulkheadStats{}, bulkhead.Stats(u.Host))
+}
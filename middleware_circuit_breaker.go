@@ -0,0 +1,278 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// CircuitOpenError is returned while the circuit of Key is open.
+type CircuitOpenError struct {
+	Key     string
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %s until %s", ErrCircuitOpen, e.Key, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+type CircuitBreakerState int
+
+const (
+	CircuitClosed CircuitBreakerState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitBreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker fails requests fast while their upstream keeps failing.
+//
+// Every key, the request host by default, has its own circuit.
+// A closed circuit opens after ConsecutiveFailures failures in a row or when
+// the failure rate within Interval reaches FailureRate.
+// After OpenTimeout the circuit lets HalfOpenRequests probe requests through,
+// it closes when all of them succeed and opens again on the first failure.
+//
+// Every attempt of a request, retries included, is counted, and a request whose circuit
+// opens is not retried: its next attempt fails with a *CircuitOpenError without being sent.
+//
+//	breaker := requests.NewCircuitBreaker()
+//	breaker.OnStateChange = func(key string, from, to requests.CircuitBreakerState) {
+//		log.Printf("circuit %s: %s -> %s", key, from, to)
+//	}
+//	client.WithCircuitBreaker(breaker)
+type CircuitBreaker struct {
+	// ConsecutiveFailures trips the circuit after that many failures in a row, zero disables it.
+	ConsecutiveFailures int
+	// FailureRate trips the circuit when failures/requests within Interval reaches it, zero disables it.
+	FailureRate float64
+	// MinRequests is the number of requests within Interval needed before FailureRate is evaluated.
+	MinRequests int
+	// Interval is the window after which the counters of a closed circuit are reset, zero never resets them.
+	Interval time.Duration
+	// OpenTimeout is how long a circuit stays open before probe requests are let through.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probe requests let through a half-open circuit.
+	HalfOpenRequests int
+	// KeyFunc returns the circuit key of a request, the host by default.
+	KeyFunc func(request *http.Request) string
+	// IsFailure reports whether a result counts as failure,
+	// by default errors and responses with status code >= 500.
+	IsFailure func(response *Response, err error) bool
+	// OnStateChange is called whenever a circuit changes its state.
+	OnStateChange func(key string, from, to CircuitBreakerState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state       CircuitBreakerState
+	generation  uint64
+	openUntil   time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	probes      int // admitted probe requests of a half-open circuit
+	successes   int // succeeded probe requests
+}
+
+type circuitTransition struct {
+	key      string
+	from, to CircuitBreakerState
+}
+
+// NewCircuitBreaker creates a CircuitBreaker which opens a circuit after 5 failures in a row
+// and lets one probe request through after 30 seconds.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		ConsecutiveFailures: 5,
+		MinRequests:         10,
+		Interval:            time.Minute,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenRequests:    1,
+	}
+}
+
+// WithCircuitBreaker adds the breaker to the client attempt hooks.
+func (c *Client) WithCircuitBreaker(breaker *CircuitBreaker) *Client {
+	return c.OnAttempt(breaker.AttemptHook())
+}
+
+// AttemptHook returns the AttemptHook of the breaker.
+func (b *CircuitBreaker) AttemptHook() AttemptHook {
+	return func(c *Client, r *http.Request) (func(*Response, error), error) {
+		key := b.key(r)
+		generation, err := b.allow(key)
+		if err != nil {
+			return nil, err
+		}
+		return func(response *Response, err error) {
+			b.report(key, generation, b.isFailure(response, err))
+		}, nil
+	}
+}
+
+// State returns the state of the circuit of key.
+func (b *CircuitBreaker) State(key string) CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cb, ok := b.circuits[key]; ok {
+		if cb.state == CircuitOpen && !time.Now().Before(cb.openUntil) {
+			return CircuitHalfOpen
+		}
+		return cb.state
+	}
+	return CircuitClosed
+}
+
+func (b *CircuitBreaker) key(r *http.Request) string {
+	if b.KeyFunc != nil {
+		return b.KeyFunc(r)
+	}
+	return r.URL.Host
+}
+
+func (b *CircuitBreaker) isFailure(response *Response, err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(response, err)
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return response != nil && response.Response != nil && response.StatusCode >= http.StatusInternalServerError
+}
+
+func (b *CircuitBreaker) allow(key string) (uint64, error) {
+	var transitions []circuitTransition
+	defer func() {
+		b.notify(transitions)
+	}()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	cb, ok := b.circuits[key]
+	if !ok {
+		cb = &circuit{windowStart: time.Now()}
+		b.circuits[key] = cb
+	}
+	now := time.Now()
+	switch cb.state {
+	case CircuitOpen:
+		if now.Before(cb.openUntil) {
+			return 0, &CircuitOpenError{Key: key, RetryAt: cb.openUntil}
+		}
+		transitions = append(transitions, b.setState(key, cb, CircuitHalfOpen, now))
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.probes >= b.halfOpenRequests() {
+			return 0, &CircuitOpenError{Key: key, RetryAt: now.Add(b.OpenTimeout)}
+		}
+		cb.probes++
+	default:
+		if b.Interval > 0 && now.Sub(cb.windowStart) > b.Interval {
+			cb.windowStart, cb.requests, cb.failures = now, 0, 0
+		}
+	}
+	return cb.generation, nil
+}
+
+func (b *CircuitBreaker) report(key string, generation uint64, failure bool) {
+	var transitions []circuitTransition
+	defer func() {
+		b.notify(transitions)
+	}()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cb, ok := b.circuits[key]
+	if !ok || cb.generation != generation {
+		// The circuit changed its state while the request was in flight.
+		return
+	}
+	now := time.Now()
+	switch cb.state {
+	case CircuitHalfOpen:
+		// The probe stays admitted, so no more than HalfOpenRequests probes
+		// are let through before the circuit closes.
+		if failure {
+			transitions = append(transitions, b.setState(key, cb, CircuitOpen, now))
+		} else if cb.successes++; cb.successes >= b.halfOpenRequests() {
+			transitions = append(transitions, b.setState(key, cb, CircuitClosed, now))
+		}
+	case CircuitClosed:
+		cb.requests++
+		if failure {
+			cb.failures++
+			cb.consecutive++
+		} else {
+			cb.consecutive = 0
+		}
+		if b.shouldTrip(cb) {
+			transitions = append(transitions, b.setState(key, cb, CircuitOpen, now))
+		}
+	}
+}
+
+func (b *CircuitBreaker) shouldTrip(cb *circuit) bool {
+	if b.ConsecutiveFailures > 0 && cb.consecutive >= b.ConsecutiveFailures {
+		return true
+	}
+	if b.FailureRate > 0 && cb.requests > 0 && cb.requests >= b.MinRequests {
+		return float64(cb.failures)/float64(cb.requests) >= b.FailureRate
+	}
+	return false
+}
+
+func (b *CircuitBreaker) setState(key string, cb *circuit, state CircuitBreakerState, now time.Time) circuitTransition {
+	transition := circuitTransition{key: key, from: cb.state, to: state}
+	cb.state = state
+	cb.generation++
+	cb.windowStart, cb.requests, cb.failures, cb.consecutive = now, 0, 0, 0
+	cb.probes, cb.successes = 0, 0
+	if state == CircuitOpen {
+		cb.openUntil = now.Add(b.OpenTimeout)
+	}
+	return transition
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests > 0 {
+		return b.HalfOpenRequests
+	}
+	return 1
+}
+
+func (b *CircuitBreaker) notify(transitions []circuitTransition) {
+	if b.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		b.OnStateChange(t.key, t.from, t.to)
+	}
+}
@@ -0,0 +1,98 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	var transitions []string
+	breaker := NewCircuitBreaker()
+	breaker.ConsecutiveFailures = 2
+	breaker.OpenTimeout = 50 * time.Millisecond
+	breaker.OnStateChange = func(key string, from, to CircuitBreakerState) {
+		require.Equal(t, u.Host, key)
+		transitions = append(transitions, from.String()+">"+to.String())
+	}
+	client := New().SetRetry(0, 0).WithCircuitBreaker(breaker)
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		_ = response.Close()
+	}
+	require.Equal(t, CircuitOpen, breaker.State(u.Host))
+	_, err := client.Get(context.Background(), server.URL, nil)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, CircuitClosed, breaker.State(u.Host))
+	require.Equal(t, []string{"closed>open", "open>half-open", "half-open>closed"}, transitions)
+}
+
+func TestCircuitBreakerRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	breaker := NewCircuitBreaker()
+	breaker.ConsecutiveFailures = 2
+	client := New().SetRetryPolicy(FixedRetryPolicy(5, time.Millisecond, RetryOnTemporaryStatus)).
+		WithCircuitBreaker(breaker)
+
+	// Every attempt is counted, the retries stop once the circuit opens.
+	_, err := client.Get(context.Background(), server.URL, nil)
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.Equal(t, u.Host, openErr.Key)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Equal(t, CircuitOpen, breaker.State(u.Host))
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	breaker := NewCircuitBreaker()
+	breaker.ConsecutiveFailures = 1
+	breaker.OpenTimeout = time.Millisecond
+	breaker.HalfOpenRequests = 2
+
+	generation, err := breaker.allow("host")
+	require.NoError(t, err)
+	breaker.report("host", generation, true)
+	time.Sleep(2 * time.Millisecond)
+
+	first, err := breaker.allow("host")
+	require.NoError(t, err)
+	breaker.report("host", first, false)
+	second, err := breaker.allow("host")
+	require.NoError(t, err)
+	// Both probes are admitted, the first success does not free its slot.
+	_, err = breaker.allow("host")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, CircuitHalfOpen, breaker.State("host"))
+	breaker.report("host", second, false)
+	require.Equal(t, CircuitClosed, breaker.State("host"))
+}
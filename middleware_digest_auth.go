@@ -0,0 +1,303 @@
+package requests
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	HttpHeaderWWWAuthenticate = `WWW-Authenticate`
+
+	AuthorizationTypeDigest = "Digest "
+)
+
+var (
+	ErrDigestChallenge = errors.New("unsupported digest challenge")
+)
+
+// digestAlgorithms are the supported algorithms, preferred first.
+var digestAlgorithms = []string{"SHA-256", "SHA-256-SESS", "MD5", "MD5-SESS"}
+
+// DigestAuth authenticates the requests of a client with HTTP Digest authentication, see RFC 7616.
+//
+// The first request to a host is sent without credentials and sent again with them
+// once the server answered 401 with a Digest challenge, so its body must be replayable.
+// The challenge is then reused for the next requests to the host, with an incremented
+// nonce count, until the server sends a new one. Each realm of a host has its own challenge,
+// a request uses the one whose domain contains its path, or else the latest one.
+//
+//	client.WithDigestAuth("admin", "secret")
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu sync.Mutex
+	// challenges are the challenges of each host, one per realm, latest last.
+	challenges map[string][]*digestChallenge
+}
+
+type digestChallenge struct {
+	realm     string
+	domain    []string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	nc        uint32
+}
+
+// NewDigestAuth creates a DigestAuth with the credentials.
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{Username: username, Password: password}
+}
+
+// WithDigestAuth authenticates every request of the client with HTTP Digest authentication.
+func (c *Client) WithDigestAuth(username, password string) *Client {
+	return c.Use(NewDigestAuth(username, password).Middleware())
+}
+
+// Middleware returns the MiddlewareFunc which answers the Digest challenges.
+func (d *DigestAuth) Middleware() MiddlewareFunc {
+	return func(c *Client, r *http.Request) (*Response, error) {
+		host := r.URL.Scheme + "://" + r.URL.Host
+		cached, err := d.authorize(r, host, nil)
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.Next(r)
+		if err != nil || response.StatusCode != http.StatusUnauthorized {
+			return response, err
+		}
+		challenge, err := parseDigestChallenge(response.Header.Values(HttpHeaderWWWAuthenticate))
+		if err != nil {
+			// Not a Digest challenge, e.g. a Basic one.
+			return response, nil
+		}
+		if cached != nil && cached.realm == challenge.realm && cached.nonce == challenge.nonce {
+			// The credentials were refused.
+			return response, nil
+		}
+		if rewindBody(r) != nil {
+			// The body was streamed, the 401 is returned as it is.
+			return response, nil
+		}
+		if _, err = d.authorize(r, host, challenge); err != nil {
+			_ = response.Close()
+			return nil, err
+		}
+		discardBody(response)
+		return c.Next(r)
+	}
+}
+
+// authorize sets the Authorization header of r for a challenge of host,
+// challenge replaces the one of its realm when it is not nil. It returns the challenge used.
+func (d *DigestAuth) authorize(r *http.Request, host string, challenge *digestChallenge) (*digestChallenge, error) {
+	d.mu.Lock()
+	if challenge != nil {
+		if d.challenges == nil {
+			d.challenges = make(map[string][]*digestChallenge)
+		}
+		challenges := d.challenges[host][:0:0]
+		for _, ch := range d.challenges[host] {
+			if ch.realm != challenge.realm {
+				challenges = append(challenges, ch)
+			}
+		}
+		d.challenges[host] = append(challenges, challenge)
+	} else {
+		challenge = digestChallengeOf(d.challenges[host], r.URL.Path)
+	}
+	if challenge == nil {
+		d.mu.Unlock()
+		return nil, nil
+	}
+	challenge.nc++
+	nc := challenge.nc
+	d.mu.Unlock()
+	authorization, err := challenge.authorization(r, d.Username, d.Password, nc)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(HttpHeaderAuthorization, authorization)
+	return challenge, nil
+}
+
+// digestChallengeOf returns the challenge whose domain contains path, or else the latest one.
+func digestChallengeOf(challenges []*digestChallenge, path string) *digestChallenge {
+	for i := len(challenges) - 1; i >= 0; i-- {
+		for _, domain := range challenges[i].domain {
+			if strings.HasPrefix(path, domain) {
+				return challenges[i]
+			}
+		}
+	}
+	if len(challenges) == 0 {
+		return nil
+	}
+	return challenges[len(challenges)-1]
+}
+
+// authorization returns the Authorization header of r with the nonce count nc.
+func (ch *digestChallenge) authorization(r *http.Request, username, password string, nc uint32) (string, error) {
+	h := digestHash(ch.algorithm)
+	cnonce := randomNonce()
+	ha1 := h(username + ":" + ch.realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(ch.algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + ch.nonce + ":" + cnonce)
+	}
+	uri := r.URL.RequestURI()
+	ha2 := h(r.Method + ":" + uri)
+	if ch.qop == "auth-int" {
+		body, err := digestBody(r)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(r.Method + ":" + uri + ":" + h(body))
+	}
+	ncValue := fmt.Sprintf("%08x", nc)
+	var response string
+	if ch.qop == "" {
+		response = h(ha1 + ":" + ch.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + ch.nonce + ":" + ncValue + ":" + cnonce + ":" + ch.qop + ":" + ha2)
+	}
+	if ch.userhash {
+		username = h(username + ":" + ch.realm)
+	}
+	var b strings.Builder
+	b.WriteString(AuthorizationTypeDigest)
+	fmt.Fprintf(&b, `username=%s, realm=%s, nonce=%s, uri=%s, algorithm=%s, response=%s`,
+		digestQuote(username), digestQuote(ch.realm), digestQuote(ch.nonce), digestQuote(uri), ch.algorithm, digestQuote(response))
+	if ch.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce=%s`, ch.qop, ncValue, digestQuote(cnonce))
+	}
+	if ch.opaque != "" {
+		fmt.Fprintf(&b, `, opaque=%s`, digestQuote(ch.opaque))
+	}
+	if ch.userhash {
+		b.WriteString(`, userhash=true`)
+	}
+	return b.String(), nil
+}
+
+// digestBody returns a copy of the body of r for qop=auth-int.
+func digestBody(r *http.Request) (string, error) {
+	var b strings.Builder
+	if err := copyBody(&b, r); err != nil {
+		return "", fmt.Errorf(`digest auth-int: %w`, err)
+	}
+	return b.String(), nil
+}
+
+func digestHash(algorithm string) func(s string) string {
+	var newHash func() hash.Hash = md5.New
+	if strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	return func(s string) string {
+		h := newHash()
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+func digestQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parseDigestChallenge returns the strongest supported Digest challenge of the WWW-Authenticate headers.
+func parseDigestChallenge(headers []string) (*digestChallenge, error) {
+	var best *digestChallenge
+	rank := len(digestAlgorithms)
+	for _, header := range headers {
+		if len(header) < len(AuthorizationTypeDigest) || !strings.EqualFold(header[:len(AuthorizationTypeDigest)], AuthorizationTypeDigest) {
+			continue
+		}
+		params := parseAuthParams(header[len(AuthorizationTypeDigest):])
+		ch := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		if ch.algorithm == "" {
+			ch.algorithm = "MD5"
+		}
+		for _, domain := range strings.Fields(params["domain"]) {
+			// The domain is a list of absolute URIs or absolute paths, see RFC 7616 section 3.3.
+			if u, err := url.Parse(domain); err == nil && u.Path != "" {
+				ch.domain = append(ch.domain, u.Path)
+			}
+		}
+		if qop, ok := params["qop"]; ok {
+			for _, q := range strings.Split(qop, ",") {
+				q = strings.ToLower(strings.TrimSpace(q))
+				if q == "auth" || (q == "auth-int" && ch.qop == "") {
+					ch.qop = q
+				}
+			}
+			if ch.qop == "" {
+				continue
+			}
+		}
+		for i, algorithm := range digestAlgorithms {
+			if strings.EqualFold(ch.algorithm, algorithm) && i < rank && ch.nonce != "" {
+				best, rank = ch, i
+			}
+		}
+	}
+	if best == nil {
+		return nil, ErrDigestChallenge
+	}
+	return best, nil
+}
+
+// parseAuthParams parses the comma separated auth-params of a challenge, names are lower cased.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		name := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i < len(s) {
+				i++
+			}
+			value, s = b.String(), s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		params[name] = value
+	}
+}
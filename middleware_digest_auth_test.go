@@ -0,0 +1,144 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newDigestServer returns a server which accepts the user "admin" with the password "secret"
+// and answers 401 with challenges, the number of which is returned by its counter.
+func newDigestServer(t *testing.T, challenges []string) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	var sent int
+	lastNC := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		authorization := r.Header.Get(HttpHeaderAuthorization)
+		if strings.HasPrefix(authorization, AuthorizationTypeDigest) {
+			p := parseAuthParams(authorization[len(AuthorizationTypeDigest):])
+			h := digestHash(p["algorithm"])
+			username := "admin"
+			if p["userhash"] == "true" {
+				username = h("admin:" + p["realm"])
+			}
+			ha1 := h("admin:" + p["realm"] + ":secret")
+			if strings.HasSuffix(strings.ToUpper(p["algorithm"]), "-SESS") {
+				ha1 = h(ha1 + ":" + p["nonce"] + ":" + p["cnonce"])
+			}
+			ha2 := h(r.Method + ":" + p["uri"])
+			if p["qop"] == "auth-int" {
+				ha2 = h(r.Method + ":" + p["uri"] + ":" + h(string(body)))
+			}
+			want := h(ha1 + ":" + p["nonce"] + ":" + ha2)
+			if p["qop"] != "" {
+				want = h(ha1 + ":" + p["nonce"] + ":" + p["nc"] + ":" + p["cnonce"] + ":" + p["qop"] + ":" + ha2)
+			}
+			if p["username"] == username && p["uri"] == r.URL.RequestURI() && p["response"] == want &&
+				(p["qop"] == "" || p["nc"] > lastNC[p["nonce"]]) && p["opaque"] == "op" {
+				lastNC[p["nonce"]] = p["nc"]
+				_, _ = fmt.Fprintf(w, "%s %s %s", p["algorithm"], p["nc"], body)
+				return
+			}
+		}
+		sent++
+		for _, challenge := range challenges {
+			w.Header().Add(HttpHeaderWWWAuthenticate, fmt.Sprintf(challenge, sent))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		challenges []string
+		algorithm  string
+		nc         []string
+	}{
+		{"md5", []string{`Digest realm="cam", nonce="n%d", qop="auth", opaque="op"`}, "MD5", nil},
+		{"md5 without qop", []string{`Digest realm="cam", nonce="n%d", opaque="op", algorithm=MD5`}, "MD5", []string{"", ""}},
+		{"md5-sess", []string{`Digest realm="cam", nonce="n%d", qop="auth", opaque="op", algorithm=MD5-sess`}, "MD5-sess", nil},
+		{"sha-256 auth-int", []string{`Digest realm="cam", nonce="n%d", qop="auth-int", opaque="op", algorithm=SHA-256`}, "SHA-256", nil},
+		{"sha-256-sess userhash", []string{`Digest realm="cam", nonce="n%d", qop="auth", opaque="op", algorithm=SHA-256-sess, userhash=true`}, "SHA-256-sess", nil},
+		{"preferred challenge", []string{
+			`Basic realm="cam"`,
+			`Digest realm="cam", nonce="n%d", qop="auth", opaque="op", algorithm=MD5`,
+			`Digest realm="cam", nonce="n%d", qop="auth,auth-int", opaque="op", algorithm=SHA-256`,
+		}, "SHA-256", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.nc == nil {
+				tt.nc = []string{"00000001", "00000002"}
+			}
+			server, challenged := newDigestServer(t, tt.challenges)
+			client := New().SetBaseURL(server.URL).WithDigestAuth("admin", "secret")
+			body, err := client.PostBytes(context.Background(), "/ptz?preset=1", "move")
+			require.NoError(t, err)
+			require.Equal(t, tt.algorithm+" "+tt.nc[0]+" move", string(body))
+			// The nonce is reused with the next nonce count.
+			body, err = client.GetBytes(context.Background(), "/status", nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.algorithm+" "+tt.nc[1]+" ", string(body))
+			require.Equal(t, 1, challenged())
+		})
+	}
+}
+
+func TestDigestAuthRefused(t *testing.T) {
+	server, challenged := newDigestServer(t, []string{`Digest realm="cam", nonce="n%d", qop="auth", opaque="op"`})
+	client := New().SetBaseURL(server.URL).WithDigestAuth("admin", "wrong")
+	response, err := client.Get(context.Background(), "/status", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	require.Equal(t, 2, challenged())
+
+	// A streamed body cannot be replayed, the challenge is returned.
+	client = New().SetBaseURL(server.URL).WithDigestAuth("admin", "secret")
+	response, err = client.Post(context.Background(), "/ptz", io.NopCloser(strings.NewReader("move")))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestDigestAuthRealms(t *testing.T) {
+	var mu sync.Mutex
+	challenged := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realm := strings.Split(r.URL.Path, "/")[1]
+		authorization := r.Header.Get(HttpHeaderAuthorization)
+		if strings.HasPrefix(authorization, AuthorizationTypeDigest) && parseAuthParams(authorization[len(AuthorizationTypeDigest):])["realm"] == realm {
+			_, _ = w.Write([]byte(realm))
+			return
+		}
+		mu.Lock()
+		challenged++
+		mu.Unlock()
+		w.Header().Set(HttpHeaderWWWAuthenticate, fmt.Sprintf(`Digest realm=%q, domain="/%s/", nonce="n", qop="auth"`, realm, realm))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).WithDigestAuth("admin", "secret")
+	// Each realm is challenged once, its challenge is then used for the paths of its domain.
+	for _, path := range []string{"/cam/status", "/nvr/status", "/cam/ptz", "/nvr/ptz"} {
+		body, err := client.GetBytes(context.Background(), path, nil)
+		require.NoError(t, err)
+		require.Equal(t, strings.Split(path, "/")[1], string(body))
+	}
+	require.Equal(t, 2, challenged)
+}
@@ -0,0 +1,230 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitByHost keys the rate limiter buckets by request host.
+func RateLimitByHost(request *http.Request) string {
+	return request.URL.Host
+}
+
+// RateLimitByRoute keys the rate limiter buckets by request method, host and path.
+func RateLimitByRoute(request *http.Request) string {
+	return request.Method + " " + request.URL.Host + request.URL.Path
+}
+
+// RateLimiter limits the requests sent by the client with token buckets.
+//
+// Every key has its own bucket which holds up to Burst tokens and refills Rate
+// tokens per second, a request waits until a token is available or its context is done.
+// When Adaptive is set, a bucket is also paused until the reset time announced by the
+// server with X-RateLimit-Remaining: 0 and X-RateLimit-Reset, or with Retry-After on a 429.
+//
+//	// 10 requests per second for every host, bursts of 20.
+//	limiter := requests.NewRateLimiter(10, 20)
+//	limiter.KeyFunc = requests.RateLimitByHost
+//	client.WithRateLimiter(limiter)
+type RateLimiter struct {
+	// Rate is the number of requests per second, zero only applies the adaptive limits.
+	Rate float64
+	// Burst is the number of requests which can be sent at once.
+	Burst int
+	// KeyFunc returns the bucket key of a request, all requests share one bucket when nil.
+	KeyFunc func(request *http.Request) string
+	// Adaptive follows the rate limit headers returned by the server.
+	Adaptive bool
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter creates an adaptive RateLimiter with one bucket for all requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{Rate: rate, Burst: burst, Adaptive: true}
+}
+
+// WithRateLimiter adds the limiter to the client attempt hooks.
+func (c *Client) WithRateLimiter(limiter *RateLimiter) *Client {
+	return c.OnAttempt(limiter.AttemptHook())
+}
+
+// AttemptHook returns the AttemptHook of the limiter.
+// Every attempt of a request takes a token, so retries wait for the limiter
+// and the pause announced by a 429 response delays the retry of that request.
+func (l *RateLimiter) AttemptHook() AttemptHook {
+	return func(c *Client, r *http.Request) (func(*Response, error), error) {
+		key := l.key(r)
+		if err := l.Wait(r.Context(), key); err != nil {
+			return nil, err
+		}
+		if !l.Adaptive {
+			return nil, nil
+		}
+		return func(response *Response, err error) {
+			if err == nil {
+				l.adapt(key, response)
+			}
+		}, nil
+	}
+}
+
+// Wait blocks until a request with key may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	wait := l.reserve(key)
+	if wait <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		l.cancel(key)
+		return fmt.Errorf(`rate limit wait of %s exceeds the context deadline: %w`, wait, context.DeadlineExceeded)
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		l.cancel(key)
+		return err
+	}
+	return nil
+}
+
+// Pause stops sending requests with key until the given time.
+// The bucket does not refill while it is paused and holds at most one token
+// when the pause ends, so the waiting requests are spread out at Rate again.
+func (l *RateLimiter) Pause(key string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(key, time.Now())
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+		b.last = until
+		if b.tokens > 1 {
+			b.tokens = 1
+		}
+	}
+}
+
+func (l *RateLimiter) key(r *http.Request) string {
+	if l.KeyFunc != nil {
+		return l.KeyFunc(r)
+	}
+	return ""
+}
+
+func (l *RateLimiter) burst() float64 {
+	if l.Burst < 1 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+// bucket returns the refilled bucket of key, l.mu must be held.
+// A paused bucket refills from the end of the pause.
+func (l *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst(), last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		if l.Rate > 0 {
+			b.tokens += elapsed.Seconds() * l.Rate
+			if b.tokens > l.burst() {
+				b.tokens = l.burst()
+			}
+		}
+		b.last = now
+	}
+	return b
+}
+
+// reserve takes a token and returns how long to wait before it can be used,
+// the wait for the token starts once the bucket is no longer paused.
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.bucket(key, now)
+	var wait time.Duration
+	if pause := b.pausedUntil.Sub(now); pause > 0 {
+		wait = pause
+	}
+	if l.Rate > 0 {
+		b.tokens--
+		if b.tokens < 0 {
+			wait += time.Duration(-b.tokens / l.Rate * float64(time.Second))
+		}
+	}
+	return wait
+}
+
+// cancel gives back the token of a request that stopped waiting.
+func (l *RateLimiter) cancel(key string) {
+	if l.Rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucket(key, time.Now()).tokens++
+}
+
+func (l *RateLimiter) adapt(key string, response *Response) {
+	if response == nil || response.Response == nil {
+		return
+	}
+	header := response.Header
+	if response.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := parseRetryAfter(header.Get(HttpHeaderRetryAfter)); ok {
+			l.Pause(key, time.Now().Add(wait))
+			return
+		}
+	}
+	remaining := headerValue(header, HttpHeaderRateLimitRemaining, "RateLimit-Remaining")
+	if remaining == "" && response.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	if n, err := strconv.Atoi(remaining); err == nil && n > 0 {
+		return
+	}
+	if until, ok := parseRateLimitReset(headerValue(header, HttpHeaderRateLimitReset, "RateLimit-Reset")); ok {
+		l.Pause(key, until)
+	}
+}
+
+func headerValue(header http.Header, keys ...string) string {
+	for _, key := range keys {
+		if v := strings.TrimSpace(header.Get(key)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseRateLimitReset parses a reset given as unix timestamp or as seconds from now.
+func parseRateLimitReset(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, false
+	}
+	// Values this large can only be unix timestamps.
+	if seconds > 1e9 {
+		return time.Unix(0, int64(seconds*float64(time.Second))), true
+	}
+	return time.Now().Add(time.Duration(seconds * float64(time.Second))), true
+}
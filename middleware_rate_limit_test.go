@@ -0,0 +1,104 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := NewRateLimiter(10, 3)
+	// The burst is sent at once, then one request every 100ms.
+	for i := 0; i < 3; i++ {
+		require.Zero(t, limiter.reserve("a"))
+	}
+	require.InDelta(t, 100*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+	require.InDelta(t, 200*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+	// Every key has its own bucket.
+	require.Zero(t, limiter.reserve("b"))
+
+	// A request which stops waiting gives its token back.
+	limiter.cancel("a")
+	require.InDelta(t, 200*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.Wait(ctx, "a"), context.DeadlineExceeded)
+	require.InDelta(t, 300*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := NewRateLimiter(10, 5)
+	limiter.Pause("a", time.Now().Add(100*time.Millisecond))
+	// The requests queued during the pause are spread out at the rate once it ends.
+	require.InDelta(t, 100*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+	require.InDelta(t, 200*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+	require.InDelta(t, 300*time.Millisecond, limiter.reserve("a"), float64(5*time.Millisecond))
+
+	// Without a rate, only the pause applies.
+	limiter = &RateLimiter{Adaptive: true}
+	limiter.Pause("", time.Now().Add(50*time.Millisecond))
+	require.InDelta(t, 50*time.Millisecond, limiter.reserve(""), float64(5*time.Millisecond))
+}
+
+func TestClientWithRateLimiterAdaptive(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.Header().Set(HttpHeaderRetryAfter, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "0.1")
+		}
+	}))
+	defer server.Close()
+
+	limiter := &RateLimiter{KeyFunc: RateLimitByHost, Adaptive: true}
+	client := New().SetRetry(0, 0).WithRateLimiter(limiter)
+	tests := []struct {
+		name string
+		wait time.Duration
+	}{
+		{"429", 0},
+		{"retry-after", time.Second},
+		{"ratelimit-reset", 100 * time.Millisecond},
+		{"resumed", 0},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		response, err := client.Get(context.Background(), server.URL, nil)
+		require.NoError(t, err, tt.name)
+		_ = response.Close()
+		require.InDelta(t, tt.wait, time.Since(start), float64(80*time.Millisecond), tt.name)
+	}
+}
+
+func TestClientWithRateLimiterRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "0.2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Every retry takes a token, the first one also waits for the announced reset.
+	client := New().SetRetryPolicy(FixedRetryPolicy(3, time.Millisecond, RetryOnTemporaryStatus)).
+		WithRateLimiter(NewRateLimiter(20, 1))
+	start := time.Now()
+	response, err := client.Get(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	require.InDelta(t, 300*time.Millisecond, time.Since(start), float64(80*time.Millisecond))
+}
@@ -0,0 +1,276 @@
+package requests
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	HttpHeaderContentTypeOctetStream = `application/octet-stream`
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Multipart is a multipart/form-data request body which is streamed to the wire,
+// files are opened only while they are sent and closed afterwards.
+//
+//	form := requests.NewMultipart().
+//		AddField("name", "gopher").
+//		AddFile("avatar", "/tmp/gopher.png").
+//		AddReader("data", "data.csv", "text/csv", reader)
+//	client.Post(ctx, "/upload", form)
+//
+// The Content-Type with the boundary is set automatically.
+// The body is replayed on retries and redirects unless a part is a reader which cannot seek.
+type Multipart struct {
+	boundary string
+	parts    []*multipartPart
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	value  string
+	path   string
+	reader io.Reader
+	offset int64
+}
+
+// NewMultipart creates an empty Multipart with a random boundary.
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+// SetBoundary overrides the random boundary.
+func (m *Multipart) SetBoundary(boundary string) error {
+	if err := multipart.NewWriter(io.Discard).SetBoundary(boundary); err != nil {
+		return err
+	}
+	m.boundary = boundary
+	return nil
+}
+
+// Boundary returns the boundary of the form.
+func (m *Multipart) Boundary() string {
+	return m.boundary
+}
+
+// ContentType returns the Content-Type of the form, including the boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// AddField adds a form field, adding a name more than once sends every value.
+func (m *Multipart) AddField(name, value string) *Multipart {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name)))
+	m.parts = append(m.parts, &multipartPart{header: header, value: value})
+	return m
+}
+
+// AddFile adds the file at path, named after its base name,
+// with a content type detected from its extension.
+func (m *Multipart) AddFile(name, path string) *Multipart {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	m.parts = append(m.parts, &multipartPart{header: fileHeader(name, filepath.Base(path), contentType), path: path})
+	return m
+}
+
+// AddReader adds a file part read from reader, application/octet-stream is used when contentType is empty.
+func (m *Multipart) AddReader(name, filename, contentType string, reader io.Reader) *Multipart {
+	return m.AddPart(fileHeader(name, filename, contentType), reader)
+}
+
+// AddPart adds a part with custom headers, e.g. a Content-ID or a Content-Transfer-Encoding.
+func (m *Multipart) AddPart(header textproto.MIMEHeader, reader io.Reader) *Multipart {
+	part := &multipartPart{header: header, reader: reader}
+	if seeker, ok := reader.(io.Seeker); ok {
+		if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			part.offset = offset
+		} else {
+			part.offset = -1
+		}
+	}
+	m.parts = append(m.parts, part)
+	return m
+}
+
+func fileHeader(name, filename, contentType string) textproto.MIMEHeader {
+	if contentType == "" {
+		contentType = HttpHeaderContentTypeOctetStream
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(name), quoteEscaper.Replace(filename)))
+	header.Set(HttpHeaderContentType, contentType)
+	return header
+}
+
+// multipartFromValues converts url.Values to a Multipart, values prefixed with `@file:` are files.
+func multipartFromValues(data url.Values) *Multipart {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	form := NewMultipart()
+	for _, k := range keys {
+		for _, v := range data[k] {
+			if strings.HasPrefix(v, HttpParamFileHolder) {
+				form.AddFile(k, strings.TrimPrefix(v, HttpParamFileHolder))
+			} else {
+				form.AddField(k, v)
+			}
+		}
+	}
+	return form
+}
+
+// replayable reports whether every part can be read again.
+func (m *Multipart) replayable() bool {
+	for _, part := range m.parts {
+		if part.reader == nil {
+			continue
+		}
+		if _, ok := part.reader.(io.Seeker); !ok || part.offset < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// size returns the length of the encoded form, or -1 when the length of a reader is unknown.
+func (m *Multipart) size() (int64, error) {
+	counter := &countWriter{}
+	w := multipart.NewWriter(counter)
+	_ = w.SetBoundary(m.boundary)
+	var content int64
+	known := true
+	for _, part := range m.parts {
+		if _, err := w.CreatePart(part.header); err != nil {
+			return 0, err
+		}
+		switch {
+		case part.path != "":
+			info, err := os.Stat(part.path)
+			if err != nil {
+				return 0, err
+			}
+			content += info.Size()
+		case part.reader != nil:
+			n, ok := readerLen(part)
+			if !ok {
+				known = false
+			}
+			content += n
+		default:
+			content += int64(len(part.value))
+		}
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	if !known {
+		return -1, nil
+	}
+	return counter.n + content, nil
+}
+
+// readerLen returns the length of a reader part from where it was added,
+// which is known for seekable and in-memory readers.
+func readerLen(part *multipartPart) (int64, bool) {
+	if seeker, ok := part.reader.(io.Seeker); ok && part.offset >= 0 {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err = seeker.Seek(current, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - part.offset, true
+	}
+	if v, ok := part.reader.(interface{ Len() int }); ok {
+		return int64(v.Len()), true
+	}
+	return 0, false
+}
+
+// Reader returns the encoded form, the parts are written on demand through an io.Pipe.
+func (m *Multipart) Reader() io.ReadCloser {
+	return &pipeReadCloser{write: m.writeTo}
+}
+
+func (m *Multipart) writeTo(pw *io.PipeWriter) {
+	w := multipart.NewWriter(pw)
+	_ = w.SetBoundary(m.boundary)
+	for _, part := range m.parts {
+		if err := m.writePart(w, part); err != nil {
+			_ = pw.CloseWithError(err)
+			return
+		}
+	}
+	_ = pw.CloseWithError(w.Close())
+}
+
+func (m *Multipart) writePart(w *multipart.Writer, part *multipartPart) error {
+	pw, err := w.CreatePart(part.header)
+	if err != nil {
+		return err
+	}
+	switch {
+	case part.path != "":
+		file, err := os.Open(part.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(pw, file)
+		return err
+	case part.reader != nil:
+		if seeker, ok := part.reader.(io.Seeker); ok && part.offset >= 0 {
+			if _, err = seeker.Seek(part.offset, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		_, err = io.Copy(pw, part.reader)
+		return err
+	}
+	_, err = io.WriteString(pw, part.value)
+	return err
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// setMultipartBody sets form as the body of request, see setRequestBody.
+func setMultipartBody(request *http.Request, form *Multipart) error {
+	size, err := form.size()
+	if err != nil {
+		return err
+	}
+	request.Body, request.ContentLength, request.GetBody = form.Reader(), size, nil
+	if form.replayable() {
+		request.GetBody = func() (io.ReadCloser, error) {
+			return form.Reader(), nil
+		}
+	}
+	return nil
+}
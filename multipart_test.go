@@ -0,0 +1,98 @@
+package requests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipart(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		reader, err := r.MultipartReader()
+		require.NoError(t, err)
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			b, _ := io.ReadAll(part)
+			parts = append(parts, strings.Join([]string{
+				part.FormName(), part.FileName(), part.Header.Get(HttpHeaderContentType), part.Header.Get("X-Part"), string(b),
+			}, "|"))
+		}
+		w.Header().Set("X-Content-Length", AnyString(r.ContentLength))
+		_, _ = w.Write([]byte(strings.Join(parts, "\n")))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="meta"`)
+	header.Set("X-Part", "1")
+	form := NewMultipart().
+		AddField("tag", "a").
+		AddField("tag", "b").
+		AddFile("file", path).
+		AddReader("data", `my "data".csv`, "text/csv", strings.NewReader("x,y")).
+		AddPart(header, strings.NewReader("{}"))
+
+	client := New().SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus))
+	response, err := client.Post(context.Background(), server.URL, form)
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, strings.Join([]string{
+		"tag||||a",
+		"tag||||b",
+		"file|hello.txt|text/plain; charset=utf-8||hello",
+		`data|my "data".csv|text/csv||x,y`,
+		"meta|||1|{}",
+	}, "\n"), response.ReadAllString())
+	size, err := form.size()
+	require.NoError(t, err)
+	require.Equal(t, AnyString(size), response.Header.Get("X-Content-Length"))
+}
+
+func TestPostFormFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		file, fileHeader, err := r.FormFile("upload")
+		require.NoError(t, err)
+		defer file.Close()
+		b, _ := io.ReadAll(file)
+		_, _ = w.Write([]byte(strings.Join(r.MultipartForm.Value["k"], ",") + "|" + fileHeader.Filename + "|" + string(b)))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	require.NoError(t, os.WriteFile(path, []byte("content"), 0o600))
+	body, err := New().PostFormBytes(context.Background(), server.URL, url.Values{
+		"k":      {"v1", "v2"},
+		"upload": {HttpParamFileHolder + path},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "v1,v2|upload.bin|content", string(body))
+
+	_, err = New().PostForm(context.Background(), server.URL, url.Values{"upload": {HttpParamFileHolder + "missing.bin"}})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,442 @@
+package requests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOAuth2ExpiryDelta = 30 * time.Second
+)
+
+var (
+	ErrOAuth2 = errors.New("oauth2 token request failed")
+)
+
+// OAuth2Error is the error response of a token endpoint, see RFC 6749 section 5.2.
+type OAuth2Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("%s: status %d", ErrOAuth2, e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+func (e *OAuth2Error) Unwrap() error {
+	return ErrOAuth2
+}
+
+// Token is an OAuth2 access token.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// Expiry is when the access token expires, zero when it never does.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token can be used for at least delta.
+func (t *Token) Valid(delta time.Duration) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry))
+}
+
+// Authorization returns the value of the Authorization header of the token.
+func (t *Token) Authorization() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, strings.TrimSpace(AuthorizationTypeBearer)) {
+		return AuthorizationTypeBearer + t.AccessToken
+	}
+	return t.TokenType + " " + t.AccessToken
+}
+
+// TokenSource returns OAuth2 tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// OAuth2AuthStyle is how the client credentials are sent to the token endpoint.
+type OAuth2AuthStyle int
+
+const (
+	// OAuth2AuthStyleHeader sends the client credentials with HTTP Basic authentication.
+	OAuth2AuthStyleHeader OAuth2AuthStyle = iota
+	// OAuth2AuthStyleParams sends the client credentials in the form body.
+	OAuth2AuthStyleParams
+)
+
+// OAuth2Config is the client registration and token endpoint of an OAuth2 grant.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	AuthStyle    OAuth2AuthStyle
+	// EndpointParams are added to every token request, e.g. "audience".
+	EndpointParams url.Values
+	// Client sends the token requests, a new client when nil.
+	// It must not use the OAuth2 middleware itself.
+	Client *Client
+}
+
+// ClientCredentialsTokenSource returns a TokenSource of the client credentials grant.
+func ClientCredentialsTokenSource(config *OAuth2Config) TokenSource {
+	return &grantTokenSource{config: config, grant: url.Values{"grant_type": {"client_credentials"}}}
+}
+
+// PasswordTokenSource returns a TokenSource of the resource owner password credentials grant,
+// the refresh token of the response is used for the next tokens when there is one.
+func PasswordTokenSource(config *OAuth2Config, username, password string) TokenSource {
+	return &grantTokenSource{config: config, grant: url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+	}}
+}
+
+// RefreshTokenSource returns a TokenSource of the refresh token grant,
+// rotated refresh tokens are used for the next tokens.
+func RefreshTokenSource(config *OAuth2Config, refreshToken string) TokenSource {
+	return &grantTokenSource{config: config, refreshToken: refreshToken}
+}
+
+// grantTokenSource requests tokens with its grant,
+// or with the refresh token of the last token when it has one.
+type grantTokenSource struct {
+	config *OAuth2Config
+	grant  url.Values
+
+	mu           sync.Mutex
+	refreshToken string
+	client       *Client
+}
+
+func (s *grantTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	refreshToken := s.refreshToken
+	s.mu.Unlock()
+	if refreshToken != "" {
+		token, err := s.refresh(ctx, &Token{RefreshToken: refreshToken})
+		if err == nil || s.grant == nil {
+			return token, err
+		}
+	}
+	return s.request(ctx, s.grant, "")
+}
+
+func (s *grantTokenSource) refresh(ctx context.Context, token *Token) (*Token, error) {
+	params := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token.RefreshToken}}
+	return s.request(ctx, params, token.RefreshToken)
+}
+
+func (s *grantTokenSource) cacheKey() string {
+	return "oauth2:" + Md5(s.config.TokenURL+"\n"+s.config.ClientID+"\n"+s.grant.Encode()+"\n"+strings.Join(s.config.Scopes, " "))
+}
+
+// request requests a token with params, the refresh token is kept when the response has none.
+func (s *grantTokenSource) request(ctx context.Context, params url.Values, refreshToken string) (*Token, error) {
+	config := s.config
+	form := url.Values{}
+	for k, v := range config.EndpointParams {
+		form[k] = append([]string(nil), v...)
+	}
+	for k, v := range params {
+		form[k] = append([]string(nil), v...)
+	}
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	client := config.Client
+	if client == nil {
+		s.mu.Lock()
+		if s.client == nil {
+			s.client = New()
+		}
+		client = s.client
+		s.mu.Unlock()
+	}
+	r := client.NewRequest(ctx, http.MethodPost, config.TokenURL, form.Encode()).
+		AsForm().
+		WithHeader(HttpHeaderAccept, HttpHeaderContentTypeJson)
+	if config.AuthStyle == OAuth2AuthStyleParams {
+		form.Set("client_id", config.ClientID)
+		if config.ClientSecret != "" {
+			form.Set("client_secret", config.ClientSecret)
+		}
+		r.Body = form.Encode()
+	} else {
+		// RFC 6749 section 2.3.1 encodes the credentials before Basic authentication.
+		credentials := url.QueryEscape(config.ClientID) + ":" + url.QueryEscape(config.ClientSecret)
+		r.WithHeader(HttpHeaderAuthorization, AuthorizationTypeBasic+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	response, err := r.Do()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuth2, err)
+	}
+	defer response.Close()
+	token, err := parseTokenResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	if token.RefreshToken != "" {
+		s.mu.Lock()
+		s.refreshToken = token.RefreshToken
+		s.mu.Unlock()
+	}
+	return token, nil
+}
+
+// parseTokenResponse parses a JSON or form encoded token response.
+func parseTokenResponse(response *Response) (*Token, error) {
+	body := response.ReadAll()
+	var raw struct {
+		OAuth2Error
+		AccessToken  string          `json:"access_token"`
+		TokenType    string          `json:"token_type"`
+		RefreshToken string          `json:"refresh_token"`
+		ExpiresIn    json.RawMessage `json:"expires_in"`
+	}
+	if parseMediaType(response.Header.Get(HttpHeaderContentType)) == HttpHeaderContentTypeForm {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOAuth2, err)
+		}
+		raw.Code, raw.Description, raw.URI = values.Get("error"), values.Get("error_description"), values.Get("error_uri")
+		raw.AccessToken, raw.TokenType, raw.RefreshToken = values.Get("access_token"), values.Get("token_type"), values.Get("refresh_token")
+		raw.ExpiresIn = json.RawMessage(values.Get("expires_in"))
+	} else if err := json.Unmarshal(body, &raw); err != nil && !response.IsError() {
+		return nil, fmt.Errorf("%w: %v", ErrOAuth2, err)
+	}
+	if response.IsError() || raw.Code != "" || raw.AccessToken == "" {
+		oauth2Err := raw.OAuth2Error
+		oauth2Err.StatusCode = response.StatusCode
+		if oauth2Err.Code == "" && raw.AccessToken == "" && !response.IsError() {
+			oauth2Err.Description = "no access_token in response"
+		}
+		return nil, &oauth2Err
+	}
+	token := &Token{AccessToken: raw.AccessToken, TokenType: raw.TokenType, RefreshToken: raw.RefreshToken}
+	// Some servers send expires_in as a string.
+	if seconds, err := strconv.ParseInt(strings.Trim(string(raw.ExpiresIn), `"`), 10, 64); err == nil && seconds > 0 {
+		token.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// OAuth2 authorizes the requests of a client with the tokens of a TokenSource.
+//
+// Tokens are reused until ExpiryDelta before they expire, concurrent requests wait for a single refresh,
+// and a request answered with 401 is sent again once with a refreshed token.
+//
+//	oauth := requests.NewOAuth2(requests.ClientCredentialsTokenSource(&requests.OAuth2Config{
+//		TokenURL:     "https://auth.example.com/oauth/token",
+//		ClientID:     "id",
+//		ClientSecret: "secret",
+//	}))
+//	oauth.Cache = requests.NewFileCache("/tmp/tokens")
+//	client.WithOAuth2(oauth)
+type OAuth2 struct {
+	Source TokenSource
+	// ExpiryDelta is how long before it expires a token is refreshed, 30 seconds when zero.
+	ExpiryDelta time.Duration
+	// Cache stores the token across clients and processes, optional.
+	Cache CacheInterface
+	// CacheKey is the key of the token in Cache, derived from the grant of the built-in sources when empty.
+	CacheKey string
+
+	mu     sync.Mutex
+	token  *Token
+	flight *oauth2Flight
+}
+
+// NewOAuth2 creates an OAuth2 of source.
+func NewOAuth2(source TokenSource) *OAuth2 {
+	return &OAuth2{Source: source}
+}
+
+// WithOAuth2 authorizes every request of the client with the tokens of o.
+func (c *Client) WithOAuth2(o *OAuth2) *Client {
+	return c.Use(o.Middleware())
+}
+
+// Token returns a token which is valid for ExpiryDelta, refreshing it when it is not.
+func (o *OAuth2) Token(ctx context.Context) (*Token, error) {
+	return o.tokenExcept(ctx, nil)
+}
+
+// tokenExcept returns a valid token other than stale, which the server refused.
+// Concurrent callers share a single fetch, the lock is not held while it runs.
+func (o *OAuth2) tokenExcept(ctx context.Context, stale *Token) (*Token, error) {
+	for {
+		o.mu.Lock()
+		delta := o.expiryDelta()
+		if o.token.Valid(delta) && !sameToken(o.token, stale) {
+			token := o.token
+			o.mu.Unlock()
+			return token, nil
+		}
+		if flight := o.flight; flight != nil {
+			o.mu.Unlock()
+			select {
+			case <-flight.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if flight.canceled && ctx.Err() == nil {
+				// The request which fetched the token was cancelled, this one fetches it again.
+				continue
+			}
+			return flight.token, flight.err
+		}
+		previous := o.token
+		if previous == nil || sameToken(previous, stale) {
+			if cached := o.cachedToken(); cached != nil {
+				if cached.Valid(delta) && !sameToken(cached, stale) {
+					o.token = cached
+					o.mu.Unlock()
+					return cached, nil
+				}
+				previous = cached
+			}
+		}
+		flight := &oauth2Flight{done: make(chan struct{})}
+		o.flight = flight
+		o.mu.Unlock()
+
+		flight.token, flight.err = o.fetch(ctx, previous)
+		flight.canceled = flight.err != nil && ctx.Err() != nil
+		if flight.err == nil {
+			o.storeToken(flight.token)
+		}
+		o.mu.Lock()
+		if flight.err == nil {
+			o.token = flight.token
+		}
+		o.flight = nil
+		o.mu.Unlock()
+		close(flight.done)
+		return flight.token, flight.err
+	}
+}
+
+// oauth2Flight is a token fetch which concurrent callers wait for.
+type oauth2Flight struct {
+	done     chan struct{}
+	token    *Token
+	err      error
+	canceled bool
+}
+
+// fetch gets a new token from the source, with the refresh token of previous when the source supports it.
+func (o *OAuth2) fetch(ctx context.Context, previous *Token) (*Token, error) {
+	if refresher, ok := o.Source.(interface {
+		refresh(ctx context.Context, token *Token) (*Token, error)
+	}); ok && previous != nil && previous.RefreshToken != "" {
+		if token, err := refresher.refresh(ctx, previous); err == nil {
+			return token, nil
+		}
+	}
+	return o.Source.Token(ctx)
+}
+
+func (o *OAuth2) expiryDelta() time.Duration {
+	if o.ExpiryDelta > 0 {
+		return o.ExpiryDelta
+	}
+	return defaultOAuth2ExpiryDelta
+}
+
+func (o *OAuth2) cacheKey() string {
+	if o.CacheKey != "" {
+		return o.CacheKey
+	}
+	if s, ok := o.Source.(interface{ cacheKey() string }); ok {
+		return s.cacheKey()
+	}
+	return ""
+}
+
+func (o *OAuth2) cachedToken() *Token {
+	key := o.cacheKey()
+	if o.Cache == nil || key == "" || !o.Cache.Has(key) {
+		return nil
+	}
+	value, err := o.Cache.Get(key)
+	if err != nil {
+		return nil
+	}
+	var token Token
+	if json.Unmarshal([]byte(value), &token) != nil || token.AccessToken == "" {
+		return nil
+	}
+	return &token
+}
+
+func (o *OAuth2) storeToken(token *Token) {
+	key := o.cacheKey()
+	if o.Cache == nil || key == "" {
+		return
+	}
+	value, err := json.Marshal(token)
+	if err != nil {
+		return
+	}
+	var ttl time.Duration
+	if !token.Expiry.IsZero() && token.RefreshToken == "" {
+		// Tokens with a refresh token are kept to be refreshed after they expire.
+		if ttl = time.Until(token.Expiry); ttl <= 0 {
+			return
+		}
+	}
+	_ = o.Cache.Set(key, string(value), ttl)
+}
+
+func sameToken(a, b *Token) bool {
+	return a != nil && b != nil && a.AccessToken == b.AccessToken
+}
+
+// Middleware returns the MiddlewareFunc which authorizes the requests.
+func (o *OAuth2) Middleware() MiddlewareFunc {
+	return func(c *Client, r *http.Request) (*Response, error) {
+		token, err := o.Token(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set(HttpHeaderAuthorization, token.Authorization())
+		response, err := c.Next(r)
+		if err != nil || response.StatusCode != http.StatusUnauthorized {
+			return response, err
+		}
+		if rewindBody(r) != nil {
+			// The body was streamed, the 401 is returned as it is.
+			return response, nil
+		}
+		if token, err = o.tokenExcept(r.Context(), token); err != nil {
+			_ = response.Close()
+			return nil, err
+		}
+		discardBody(response)
+		r.Header.Set(HttpHeaderAuthorization, token.Authorization())
+		return c.Next(r)
+	}
+}
@@ -0,0 +1,172 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type oauth2Server struct {
+	*httptest.Server
+	expiresIn int
+	grants    []string
+	mu        sync.Mutex
+	current   string
+	issued    int32
+}
+
+func newOAuth2Server(t *testing.T, expiresIn int) *oauth2Server {
+	s := &oauth2Server{expiresIn: expiresIn}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "id" || secret != "s%C3%A9cret" {
+			w.Header().Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+			return
+		}
+		_ = r.ParseForm()
+		n := atomic.AddInt32(&s.issued, 1)
+		s.mu.Lock()
+		s.grants = append(s.grants, r.PostForm.Get("grant_type"))
+		s.current = fmt.Sprintf("token-%d", n)
+		token := s.current
+		s.mu.Unlock()
+		w.Header().Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+		_, _ = fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer","refresh_token":"refresh-%d","expires_in":"%d"}`, token, n, s.expiresIn)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		valid := r.Header.Get(HttpHeaderAuthorization) == AuthorizationTypeBearer+s.current
+		s.mu.Unlock()
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte(r.Header.Get(HttpHeaderAuthorization)+" "), body...))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *oauth2Server) config(secret string) *OAuth2Config {
+	return &OAuth2Config{TokenURL: s.URL + "/token", ClientID: "id", ClientSecret: secret, Scopes: []string{"read"}}
+}
+
+func (s *oauth2Server) revoke() {
+	s.mu.Lock()
+	s.current = "revoked"
+	s.mu.Unlock()
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	server := newOAuth2Server(t, 3600)
+	client := New().SetBaseURL(server.URL).WithOAuth2(NewOAuth2(ClientCredentialsTokenSource(server.config("sécret"))))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := client.GetBytes(context.Background(), "/api", nil)
+			require.NoError(t, err)
+			require.Equal(t, "Bearer token-1 ", string(body))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, []string{"client_credentials"}, server.grants)
+
+	// A revoked token is refreshed once and the request replayed with its body.
+	server.revoke()
+	body, err := client.PostBytes(context.Background(), "/api", "payload")
+	require.NoError(t, err)
+	require.Equal(t, "Bearer token-2 payload", string(body))
+	require.Equal(t, []string{"client_credentials", "refresh_token"}, server.grants)
+}
+
+func TestOAuth2ProactiveRefreshAndCache(t *testing.T) {
+	server := newOAuth2Server(t, 10)
+	cache := NewFileCache(t.TempDir() + "/")
+	oauth := NewOAuth2(PasswordTokenSource(server.config("sécret"), "user", "pass"))
+	oauth.Cache = cache
+	token, err := oauth.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token.AccessToken)
+	// The token expires within ExpiryDelta, it is refreshed before it is used.
+	token, err = oauth.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token.AccessToken)
+	require.Equal(t, []string{"password", "refresh_token"}, server.grants)
+
+	// Another OAuth2 with the same cache and a longer lifetime reuses the cached token.
+	other := NewOAuth2(PasswordTokenSource(server.config("sécret"), "user", "pass"))
+	other.Cache, other.ExpiryDelta = cache, 1
+	token, err = other.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token.AccessToken)
+	require.Len(t, server.grants, 2)
+}
+
+func TestOAuth2Error(t *testing.T) {
+	server := newOAuth2Server(t, 3600)
+	client := New().SetBaseURL(server.URL).WithOAuth2(NewOAuth2(RefreshTokenSource(server.config("wrong"), "refresh")))
+	_, err := client.GetBytes(context.Background(), "/api", nil)
+	require.True(t, errors.Is(err, ErrOAuth2))
+	var oauth2Err *OAuth2Error
+	require.True(t, errors.As(err, &oauth2Err))
+	require.Equal(t, http.StatusUnauthorized, oauth2Err.StatusCode)
+	require.Equal(t, "invalid_client", oauth2Err.Code)
+}
+
+type blockingTokenSource struct {
+	fetches int32
+	release chan struct{}
+}
+
+func (s *blockingTokenSource) Token(ctx context.Context) (*Token, error) {
+	n := atomic.AddInt32(&s.fetches, 1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &Token{AccessToken: fmt.Sprintf("token-%d", n)}, nil
+}
+
+func TestOAuth2SingleFlight(t *testing.T) {
+	source := &blockingTokenSource{release: make(chan struct{})}
+	oauth := NewOAuth2(source)
+	tokens := make(chan string, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			token, err := oauth.Token(context.Background())
+			require.NoError(t, err)
+			tokens <- token.AccessToken
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&source.fetches) == 1 }, time.Second, time.Millisecond)
+
+	// A caller which gives up does not wait for the fetch in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := oauth.Token(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(source.release)
+	for i := 0; i < 5; i++ {
+		require.Equal(t, "token-1", <-tokens)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&source.fetches))
+}
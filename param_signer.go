@@ -0,0 +1,354 @@
+package requests
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParamSignType is the hash of a ParamSigner, the values are the sign_type of the payment gateways.
+type ParamSignType string
+
+const (
+	ParamSignMD5        ParamSignType = "MD5"
+	ParamSignSHA1       ParamSignType = "SHA1"
+	ParamSignSHA256     ParamSignType = "SHA256"
+	ParamSignHMACSHA256 ParamSignType = "HMAC-SHA256"
+
+	// DefaultParamSignTemplate is the string to sign of a ParamSigner without a Template.
+	DefaultParamSignTemplate = "{params}&key={key}"
+)
+
+var (
+	ErrParamSign = errors.New("invalid parameter signature")
+)
+
+// ParamSigner signs the parameters of requests the way payment gateways do:
+// the parameters are sorted by key, joined as "k=v&..." and hashed together with the key,
+// the signature is then added to the parameters in Field.
+//
+// The parameters are the form, JSON or XML body of a request, or its query when it has
+// no such body, so url.Values, map[string]string and struct bodies are all signed once encoded.
+// A JSON body must be an object and an XML body a flat document such as <xml><k>v</k></xml>,
+// nested JSON values are signed as compact JSON.
+//
+//	signer := requests.NewParamSigner("secret", requests.ParamSignMD5)
+//	signer.Exclude = []string{"sign_type"}
+//	client.WithParamSigner(signer)
+type ParamSigner struct {
+	Key string
+	// SignType is the hash of the string to sign, ParamSignMD5 when empty.
+	// ParamSignHMACSHA256 uses Key as the HMAC key.
+	SignType ParamSignType
+	// Template is the string to sign, {params} is replaced by the sorted parameters
+	// and {key} by Key, DefaultParamSignTemplate when empty.
+	Template string
+	// Field receives the signature, "sign" when empty. It is never signed.
+	Field string
+	// Exclude are the keys which are not signed.
+	Exclude []string
+	// KeepEmpty signs the parameters with an empty value, they are skipped by default.
+	KeepEmpty bool
+	// Lowercase sends the signature in lower case, it is upper cased by default.
+	Lowercase bool
+}
+
+// NewParamSigner creates a ParamSigner with key which adds an upper cased "sign" parameter.
+func NewParamSigner(key string, signType ParamSignType) *ParamSigner {
+	return &ParamSigner{Key: key, SignType: signType}
+}
+
+// WithParamSigner signs the parameters of every attempt of the requests of the client with signer, see WithSigner.
+func (c *Client) WithParamSigner(signer *ParamSigner) *Client {
+	return c.WithSigner(signer)
+}
+
+// Middleware returns the MiddlewareFunc which adds the signature to the parameters of the requests,
+// once for all their attempts unlike WithParamSigner.
+func (s *ParamSigner) Middleware() MiddlewareFunc {
+	return func(c *Client, r *http.Request) (*Response, error) {
+		if err := s.Sign(r); err != nil {
+			return nil, err
+		}
+		return c.Next(r)
+	}
+}
+
+// StringToSign returns the string which is signed for params.
+func (s *ParamSigner) StringToSign(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if k == s.field() || (v == "" && !s.KeepEmpty) || s.excluded(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params[k])
+	}
+	template := s.Template
+	if template == "" {
+		template = DefaultParamSignTemplate
+	}
+	return strings.NewReplacer("{params}", strings.Join(pairs, "&"), "{key}", s.Key).Replace(template)
+}
+
+// Signature returns the signature of params.
+func (s *ParamSigner) Signature(params map[string]string) string {
+	stringToSign := s.StringToSign(params)
+	var signature string
+	switch s.SignType {
+	case ParamSignSHA1:
+		signature = Sha1(stringToSign)
+	case ParamSignSHA256:
+		signature = Sha256(stringToSign)
+	case ParamSignHMACSHA256:
+		signature = HmacSha256(s.Key, stringToSign)
+	default:
+		signature = Md5(stringToSign)
+	}
+	if s.Lowercase {
+		return strings.ToLower(signature)
+	}
+	return strings.ToUpper(signature)
+}
+
+// Verify checks the signature in the Field of params, the case of the signature is ignored.
+func (s *ParamSigner) Verify(params map[string]string) error {
+	signature, ok := params[s.field()]
+	if !ok || signature == "" {
+		return fmt.Errorf(`%w: no "%s" parameter`, ErrParamSign, s.field())
+	}
+	expected := strings.ToUpper(s.Signature(params))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToUpper(signature))) != 1 {
+		return ErrParamSign
+	}
+	return nil
+}
+
+// VerifyResponse checks the signature of the form, JSON or XML body of response.
+// The body is read and put back, so the response can still be unmarshalled.
+func (s *ParamSigner) VerifyResponse(response *Response) error {
+	if response == nil || response.Response == nil {
+		return fmt.Errorf(`%w: no response`, ErrParamSign)
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	contentType := response.ContentType()
+	if response.req != nil && response.req.forceContentType != "" {
+		contentType = response.req.forceContentType
+	}
+	var params map[string]string
+	switch trimmed := bytes.TrimSpace(body); {
+	case IsJSONType(contentType) || (len(trimmed) > 0 && trimmed[0] == '{'):
+		_, params, err = parseJSONParams(body)
+	case IsXMLType(contentType) || (len(trimmed) > 0 && trimmed[0] == '<'):
+		_, params, err = parseXMLParams(body)
+	default:
+		var values url.Values
+		values, err = url.ParseQuery(string(trimmed))
+		params = firstValues(values)
+	}
+	if err != nil {
+		return fmt.Errorf(`%w: %v`, ErrParamSign, err)
+	}
+	return s.Verify(params)
+}
+
+// Sign adds the signature to the form, JSON or XML body of request, or else to its query,
+// replacing the one of a previous attempt. The body must be replayable and not compressed.
+func (s *ParamSigner) Sign(request *http.Request) error {
+	if !hasBody(request) {
+		s.signQuery(request)
+		return nil
+	}
+	contentType := parseMediaType(request.Header.Get(HttpHeaderContentType))
+	if contentType != HttpHeaderContentTypeForm && !IsJSONType(contentType) && !IsXMLType(contentType) {
+		// Multipart and binary bodies have no parameters.
+		s.signQuery(request)
+		return nil
+	}
+	if request.Header.Get(HttpHeaderContentEncoding) != "" {
+		return fmt.Errorf(`param sign: the body is encoded with %s`, request.Header.Get(HttpHeaderContentEncoding))
+	}
+	var buf bytes.Buffer
+	if err := copyBody(&buf, request); err != nil {
+		return fmt.Errorf(`param sign: %w`, err)
+	}
+	body, err := s.signBody(contentType, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf(`param sign: %w`, err)
+	}
+	if err = setRequestBody(request, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	if r := requestFromContext(request.Context()); r != nil {
+		setUploadProgress(request, r.uploadProgress)
+	}
+	return nil
+}
+
+func (s *ParamSigner) signQuery(request *http.Request) {
+	signature := s.Signature(firstValues(request.URL.Query()))
+	request.URL.RawQuery = mergeRawQuery(request.URL.RawQuery, QueryMergeReplace, url.Values{s.field(): {signature}})
+}
+
+// signBody returns body of contentType with the signature of its parameters.
+func (s *ParamSigner) signBody(contentType string, body []byte) ([]byte, error) {
+	switch {
+	case IsJSONType(contentType):
+		raw, params, err := parseJSONParams(body)
+		if err != nil {
+			return nil, err
+		}
+		signature, _ := json.Marshal(s.Signature(params))
+		raw[s.field()] = signature
+		return json.Marshal(raw)
+	case IsXMLType(contentType):
+		root, params, err := parseXMLParams(body)
+		if err != nil {
+			return nil, err
+		}
+		params[s.field()] = s.Signature(params)
+		return encodeXMLParams(root, params), nil
+	default:
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		values.Set(s.field(), s.Signature(firstValues(values)))
+		return []byte(values.Encode()), nil
+	}
+}
+
+func (s *ParamSigner) field() string {
+	if s.Field != "" {
+		return s.Field
+	}
+	return "sign"
+}
+
+func (s *ParamSigner) excluded(key string) bool {
+	for _, k := range s.Exclude {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// firstValues returns the first value of every key of values.
+func firstValues(values url.Values) map[string]string {
+	params := make(map[string]string, len(values))
+	for k, vs := range values {
+		if len(vs) > 0 {
+			params[k] = vs[0]
+		}
+	}
+	return params
+}
+
+// parseJSONParams parses a JSON object, strings are unquoted, null is empty
+// and any other value is kept as compact JSON.
+func parseJSONParams(body []byte) (map[string]json.RawMessage, map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, err
+	}
+	if raw == nil {
+		raw = make(map[string]json.RawMessage)
+	}
+	params := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch {
+		case bytes.Equal(v, []byte("null")):
+			params[k] = ""
+		case len(v) > 0 && v[0] == '"':
+			var str string
+			if err := json.Unmarshal(v, &str); err != nil {
+				return nil, nil, err
+			}
+			params[k] = str
+		default:
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, v); err != nil {
+				return nil, nil, err
+			}
+			params[k] = buf.String()
+		}
+	}
+	return raw, params, nil
+}
+
+// parseXMLParams parses a flat XML document and returns the name of its root and the text of its children.
+func parseXMLParams(body []byte) (string, map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	params := make(map[string]string)
+	var root, key string
+	var text strings.Builder
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				root = t.Name.Local
+			} else if depth == 2 {
+				key = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if depth == 2 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				params[key] = text.String()
+			}
+			depth--
+		}
+	}
+	if root == "" {
+		return "", nil, errors.New("no XML root element")
+	}
+	return root, params, nil
+}
+
+// encodeXMLParams encodes params as the children of root, sorted by key.
+func encodeXMLParams(root string, params map[string]string) []byte {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	b.WriteString("<" + root + ">")
+	for _, k := range keys {
+		b.WriteString("<" + k + ">")
+		_ = xml.EscapeText(&b, []byte(params[k]))
+		b.WriteString("</" + k + ">")
+	}
+	b.WriteString("</" + root + ">")
+	return b.Bytes()
+}
@@ -0,0 +1,137 @@
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamSignerSign(t *testing.T) {
+	// The signature example of the WeChat Pay v2 documentation.
+	params := map[string]string{
+		"appid":       "wxd930ea5d5a258f4f",
+		"mch_id":      "10000100",
+		"device_info": "1000",
+		"body":        "test",
+		"nonce_str":   "ibuaiVcKdpRxkhJA",
+		"attach":      "",
+		"sign":        "ignored",
+	}
+	signer := NewParamSigner("192006250b4c09247ec02edce69f6a2d", ParamSignMD5)
+	require.Equal(t, "appid=wxd930ea5d5a258f4f&body=test&device_info=1000&mch_id=10000100&nonce_str=ibuaiVcKdpRxkhJA&key=192006250b4c09247ec02edce69f6a2d",
+		signer.StringToSign(params))
+	require.Equal(t, "9A0A8659F005D6984697E2CA0A9CF3B7", signer.Signature(params))
+	signer.SignType = ParamSignHMACSHA256
+	require.Equal(t, "6A9AE1657590FD6257D693A078E1C3E4BB6BA4DC30B23E0EE2496E54170DACD6", signer.Signature(params))
+
+	signer = &ParamSigner{Key: "secret", SignType: ParamSignSHA256, Template: "{params}{key}", Field: "signature",
+		Exclude: []string{"sign_type"}, KeepEmpty: true, Lowercase: true}
+	params = map[string]string{"b": "2", "a": "", "sign_type": "SHA256", "signature": "x"}
+	require.Equal(t, "a=&b=2secret", signer.StringToSign(params))
+	require.Equal(t, Sha256("a=&b=2secret"), signer.Signature(params))
+
+	params["signature"] = signer.Signature(params)
+	require.NoError(t, signer.Verify(params))
+	params["b"] = "3"
+	require.ErrorIs(t, signer.Verify(params), ErrParamSign)
+	delete(params, "signature")
+	require.ErrorIs(t, signer.Verify(params), ErrParamSign)
+}
+
+func TestClientWithParamSigner(t *testing.T) {
+	signer := NewParamSigner("secret", ParamSignMD5)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var params map[string]string
+		var err error
+		switch {
+		case len(body) == 0:
+			params = firstValues(r.URL.Query())
+		case IsJSONType(r.Header.Get(HttpHeaderContentType)):
+			_, params, err = parseJSONParams(body)
+		case IsXMLType(r.Header.Get(HttpHeaderContentType)):
+			_, params, err = parseXMLParams(body)
+		default:
+			var values url.Values
+			values, err = url.ParseQuery(string(body))
+			params = firstValues(values)
+		}
+		require.NoError(t, err)
+		if signer.Verify(params) != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		response := map[string]string{"code": "SUCCESS", "echo": string(body)}
+		response["sign"] = signer.Signature(response)
+		w.Header().Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	type order struct {
+		OutTradeNo string         `json:"out_trade_no"`
+		TotalFee   int            `json:"total_fee"`
+		Detail     map[string]int `json:"detail"`
+	}
+	client := New().SetBaseURL(server.URL).WithParamSigner(signer)
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        any
+		echo        string
+	}{
+		{"form", http.MethodPost, "", url.Values{"b": {"2"}, "a": {"1"}, "empty": {""}},
+			"a=1&b=2&empty=&sign=" + signer.Signature(map[string]string{"a": "1", "b": "2"})},
+		{"json", http.MethodPost, HttpHeaderContentTypeJson, order{OutTradeNo: "1217752501", TotalFee: 1, Detail: map[string]int{"x": 1}},
+			`{"detail":{"x":1},"out_trade_no":"1217752501","sign":"` +
+				signer.Signature(map[string]string{"detail": `{"x":1}`, "out_trade_no": "1217752501", "total_fee": "1"}) + `","total_fee":1}`},
+		{"xml", http.MethodPost, HttpHeaderContentTypeXml, `<xml><mch_id>10000100</mch_id><body><![CDATA[a&b]]></body></xml>`,
+			"<xml><body>a&amp;b</body><mch_id>10000100</mch_id><sign>" + signer.Signature(map[string]string{"body": "a&b", "mch_id": "10000100"}) + "</sign></xml>"},
+		{"query", http.MethodGet, "", map[string]string{"id": "1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := client.NewRequest(context.Background(), tt.method, "/pay", tt.body)
+			if tt.contentType != "" {
+				request.WithContentType(tt.contentType)
+			}
+			response, err := request.Do()
+			require.NoError(t, err)
+			defer response.Close()
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.NoError(t, signer.VerifyResponse(response))
+			var result map[string]string
+			require.NoError(t, response.Unmarshal(&result))
+			require.Equal(t, tt.echo, result["echo"])
+		})
+	}
+}
+
+func TestClientWithParamSignerEveryAttempt(t *testing.T) {
+	signer := NewParamSigner("secret", ParamSignMD5)
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	// The signature of the first attempt is replaced on the retry, not signed.
+	client := New().SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus)).WithParamSigner(signer)
+	response, err := client.Post(context.Background(), server.URL, url.Values{"a": {"1"}})
+	require.NoError(t, err)
+	_ = response.Close()
+	signed := "a=1&sign=" + signer.Signature(map[string]string{"a": "1"})
+	require.Equal(t, []string{signed, signed}, bodies)
+}
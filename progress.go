@@ -0,0 +1,170 @@
+package requests
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum interval between two progress reports of a transfer.
+const progressInterval = 100 * time.Millisecond
+
+// Progress is a snapshot of a request body upload or a response body download.
+type Progress struct {
+	// Upload is true for request bodies and false for response bodies.
+	Upload bool
+	// Current is the number of bytes transferred.
+	Current int64
+	// Total is the size of the body, -1 when it is unknown.
+	Total int64
+	// Rate is the average transfer rate in bytes per second.
+	Rate float64
+	// ETA is the estimated remaining time, zero when the size is unknown.
+	ETA time.Duration
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+	// Done is true for the last report of the transfer.
+	Done bool
+}
+
+// Percent returns the completed percentage, or -1 when the size is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Current) * 100 / float64(p.Total)
+}
+
+// ProgressFunc is called while a body is transferred, at most every 100ms and once more when it is done.
+type ProgressFunc func(p Progress)
+
+// ProgressChan returns a ProgressFunc which sends to ch, reports are dropped while ch is full
+// so a slow reader never blocks the transfer.
+func ProgressChan(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// SetUploadProgress sets the function which reports the progress of request bodies.
+//
+//	client.SetUploadProgress(func(p requests.Progress) {
+//		fmt.Printf("\r%.1f%% %.0f B/s ETA %v", p.Percent(), p.Rate, p.ETA)
+//	})
+func (c *Client) SetUploadProgress(fn ProgressFunc) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.uploadProgress = fn
+	return c
+}
+
+// SetDownloadProgress sets the function which reports the progress of response bodies,
+// the Downloader reports the progress of the whole file with it.
+func (c *Client) SetDownloadProgress(fn ProgressFunc) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.downloadProgress = fn
+	return c
+}
+
+// SetUploadProgress overrides the client upload progress function for this request.
+func (r *ClientRequest) SetUploadProgress(fn ProgressFunc) *ClientRequest {
+	r.uploadProgress = fn
+	return r
+}
+
+// SetDownloadProgress overrides the client download progress function for this request.
+func (r *ClientRequest) SetDownloadProgress(fn ProgressFunc) *ClientRequest {
+	r.downloadProgress = fn
+	return r
+}
+
+// progressTracker accumulates the transferred bytes of one transfer and reports them.
+type progressTracker struct {
+	fn     ProgressFunc
+	upload bool
+	total  int64
+
+	mu      sync.Mutex
+	start   time.Time
+	last    time.Time
+	current int64
+	done    bool
+}
+
+func newProgressTracker(fn ProgressFunc, upload bool, total int64) *progressTracker {
+	if total < 0 {
+		total = -1
+	}
+	now := time.Now()
+	return &progressTracker{fn: fn, upload: upload, total: total, start: now, last: now}
+}
+
+func (t *progressTracker) add(n int, eof bool) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	t.current += int64(n)
+	now := time.Now()
+	t.done = eof || (t.total > 0 && t.current >= t.total)
+	if !t.done && now.Sub(t.last) < progressInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.last = now
+	p := Progress{Upload: t.upload, Current: t.current, Total: t.total, Elapsed: now.Sub(t.start), Done: t.done}
+	t.mu.Unlock()
+	if seconds := p.Elapsed.Seconds(); seconds > 0 {
+		p.Rate = float64(p.Current) / seconds
+	}
+	if p.Total > 0 && p.Rate > 0 && p.Current < p.Total {
+		p.ETA = time.Duration(float64(p.Total-p.Current) / p.Rate * float64(time.Second))
+	}
+	t.fn(p)
+}
+
+// progressReadCloser reports every read of a body to its tracker,
+// the end of a part of a larger transfer does not finish it.
+type progressReadCloser struct {
+	io.ReadCloser
+	tracker *progressTracker
+	part    bool
+}
+
+func (b *progressReadCloser) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.tracker.add(n, err == io.EOF && !b.part)
+	return n, err
+}
+
+// setUploadProgress reports the progress of the body of request,
+// every attempt of a retried request starts over.
+func setUploadProgress(request *http.Request, fn ProgressFunc) {
+	if fn == nil || !hasBody(request) {
+		return
+	}
+	request.Body = &progressReadCloser{ReadCloser: request.Body, tracker: newProgressTracker(fn, true, request.ContentLength)}
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &progressReadCloser{ReadCloser: body, tracker: newProgressTracker(fn, true, request.ContentLength)}, nil
+		}
+	}
+}
+
+// setDownloadProgress reports the progress of the body of response.
+func setDownloadProgress(response *http.Response, fn ProgressFunc) {
+	if fn == nil || response.Body == nil || response.Body == http.NoBody {
+		return
+	}
+	response.Body = &progressReadCloser{ReadCloser: response.Body, tracker: newProgressTracker(fn, false, response.ContentLength)}
+}
@@ -0,0 +1,83 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	content := strings.Repeat("x", 64<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		http.ServeContent(w, r, "file.txt", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	var (
+		mu        sync.Mutex
+		uploads   []Progress
+		downloads = make(chan Progress, 100)
+	)
+	client := New().SetDownloadProgress(ProgressChan(downloads))
+	response, err := client.NewRequest(context.Background(), http.MethodPost, server.URL, strings.NewReader("upload body")).
+		SetUploadProgress(func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			uploads = append(uploads, p)
+		}).
+		Do()
+	require.NoError(t, err)
+	require.Equal(t, content, response.ReadAllString())
+	_ = response.Close()
+
+	mu.Lock()
+	last := uploads[len(uploads)-1]
+	mu.Unlock()
+	require.True(t, last.Upload)
+	require.True(t, last.Done)
+	require.Equal(t, int64(11), last.Current)
+	require.Equal(t, int64(11), last.Total)
+
+	close(downloads)
+	for p := range downloads {
+		last = p
+	}
+	require.False(t, last.Upload)
+	require.True(t, last.Done)
+	require.Equal(t, int64(len(content)), last.Current)
+	require.Equal(t, float64(100), last.Percent())
+
+	// The downloader reports the whole file once, not every range request.
+	var reports []Progress
+	fileName := filepath.Join(t.TempDir(), "file.txt")
+	downloader := NewDownloader(New(), context.Background(), server.URL, fileName, 4)
+	downloader.Progress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	}
+	require.NoError(t, downloader.Download())
+	b, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal([]byte(content), b))
+	var done int
+	for _, p := range reports {
+		if p.Done {
+			done++
+			last = p
+		}
+	}
+	require.Equal(t, 1, done)
+	require.Equal(t, int64(len(content)), last.Current)
+}
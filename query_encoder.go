@@ -0,0 +1,336 @@
+package requests
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedQueryType = errors.New("unsupported query type")
+
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// ArrayFormat is how QueryEncoder encodes slices and arrays.
+type ArrayFormat int
+
+const (
+	// ArrayFormatRepeat repeats the key, `a=1&a=2`.
+	ArrayFormatRepeat ArrayFormat = iota
+	// ArrayFormatBrackets appends brackets to the key, `a[]=1&a[]=2`.
+	ArrayFormatBrackets
+	// ArrayFormatComma joins the values with commas, `a=1,2`.
+	ArrayFormatComma
+	// ArrayFormatIndexed appends the index to the key, `a[0]=1&a[1]=2`.
+	ArrayFormatIndexed
+)
+
+// NestedFormat is how QueryEncoder encodes the keys of nested structs and maps.
+type NestedFormat int
+
+const (
+	// NestedFormatBrackets encodes nested keys as `user[name]=gopher`.
+	NestedFormatBrackets NestedFormat = iota
+	// NestedFormatDots encodes nested keys as `user.name=gopher`.
+	NestedFormatDots
+)
+
+// QueryEncoder encodes structs and maps into url.Values.
+//
+// Struct fields are named by their `url` tag, then by their `json` tag and then by the field name.
+// The tag options are:
+//
+//	Name  string    `url:"name"`            // name=...
+//	Page  int       `url:"page,omitempty"`  // omitted when zero
+//	Since time.Time `url:"since,unix"`      // seconds since epoch instead of TimeFormat
+//	Token string    `url:"-"`               // never encoded
+//
+// Map keys are sorted, so the same value is always encoded to the same query.
+type QueryEncoder struct {
+	ArrayFormat  ArrayFormat
+	NestedFormat NestedFormat
+	// TimeFormat is the layout of time.Time values, time.RFC3339 when empty.
+	TimeFormat string
+}
+
+// NewQueryEncoder creates a QueryEncoder with the default formats.
+func NewQueryEncoder() *QueryEncoder {
+	return &QueryEncoder{TimeFormat: time.RFC3339}
+}
+
+// SetQueryEncoder sets the encoder of struct and map query parameters.
+func (c *Client) SetQueryEncoder(encoder *QueryEncoder) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.queryEncoder = encoder
+	return c
+}
+
+// WithQueryStruct adds the fields of v, a struct or a map, to the query of every request.
+// It is the struct counterpart of WithQueryKV, whose KVCallback only returns a single pair.
+func (c *Client) WithQueryStruct(v any) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.queryStructs = append(c.queryStructs, v)
+	return c
+}
+
+// WithQueryStruct adds the fields of v, a struct or a map, to the query of this request only.
+func (r *ClientRequest) WithQueryStruct(v any) *ClientRequest {
+	r.queryStructs = append(r.queryStructs, v)
+	return r
+}
+
+// Encode returns the sorted query string of v.
+func (e *QueryEncoder) Encode(v any) (string, error) {
+	values, err := e.Values(v)
+	if err != nil {
+		return "", err
+	}
+	return values.Encode(), nil
+}
+
+// Values encodes v, a struct or a map with string keys, into url.Values.
+func (e *QueryEncoder) Values(v any) (url.Values, error) {
+	values := make(url.Values)
+	rv := indirectValue(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return values, nil
+	}
+	if !isQueryObject(rv) {
+		return nil, fmt.Errorf(`%w: %s`, ErrUnsupportedQueryType, rv.Type())
+	}
+	return values, e.encode(values, "", rv, queryTagOptions{})
+}
+
+type queryTagOptions struct {
+	omitEmpty bool
+	unix      bool
+}
+
+func (e *QueryEncoder) encode(values url.Values, key string, rv reflect.Value, opts queryTagOptions) error {
+	rv = indirectValue(rv)
+	if !rv.IsValid() {
+		if !opts.omitEmpty {
+			values.Add(key, "")
+		}
+		return nil
+	}
+	if opts.omitEmpty && isEmptyValue(rv) {
+		return nil
+	}
+	if s, ok, err := e.scalar(rv, opts); ok || err != nil {
+		if err == nil {
+			values.Add(key, s)
+		}
+		return err
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return e.encodeStruct(values, key, rv)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf(`%w: %s`, ErrUnsupportedQueryType, rv.Type())
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if err := e.encode(values, e.nestedKey(key, k.String()), rv.MapIndex(k), queryTagOptions{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		return e.encodeArray(values, key, rv, opts)
+	}
+	return fmt.Errorf(`%w: %s`, ErrUnsupportedQueryType, rv.Type())
+}
+
+func (e *QueryEncoder) encodeStruct(values url.Values, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, opts, skip := queryFieldName(field)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			if ev := indirectValue(fv); ev.IsValid() && ev.Kind() == reflect.Struct {
+				if err := e.encodeStruct(values, prefix, ev); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if err := e.encode(values, e.nestedKey(prefix, name), fv, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *QueryEncoder) encodeArray(values url.Values, key string, rv reflect.Value, opts queryTagOptions) error {
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		values.Add(key, string(rv.Bytes()))
+		return nil
+	}
+	opts.omitEmpty = false
+	if e.ArrayFormat == ArrayFormatComma {
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			s, ok, err := e.scalar(indirectValue(rv.Index(i)), opts)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return e.encodeIndexed(values, key, rv, opts)
+			}
+			items = append(items, s)
+		}
+		values.Add(key, strings.Join(items, ","))
+		return nil
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item := indirectValue(rv.Index(i))
+		if _, ok, _ := e.scalar(item, opts); !ok && item.IsValid() {
+			// Structs and maps in arrays are always indexed, other formats would merge their fields.
+			return e.encodeIndexed(values, key, rv, opts)
+		}
+	}
+	switch e.ArrayFormat {
+	case ArrayFormatBrackets:
+		key += "[]"
+	case ArrayFormatIndexed:
+		return e.encodeIndexed(values, key, rv, opts)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := e.encode(values, key, rv.Index(i), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *QueryEncoder) encodeIndexed(values url.Values, key string, rv reflect.Value, opts queryTagOptions) error {
+	for i := 0; i < rv.Len(); i++ {
+		if err := e.encode(values, fmt.Sprintf("%s[%d]", key, i), rv.Index(i), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scalar formats rv when it is encoded as a single value.
+func (e *QueryEncoder) scalar(rv reflect.Value, opts queryTagOptions) (string, bool, error) {
+	if !rv.IsValid() {
+		return "", true, nil
+	}
+	if rv.Type() == timeType {
+		t := rv.Interface().(time.Time)
+		if opts.unix {
+			return strconv.FormatInt(t.Unix(), 10), true, nil
+		}
+		layout := e.TimeFormat
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return t.Format(layout), true, nil
+	}
+	if rv.Type().Implements(textMarshalerType) {
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), true, err
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), true, nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), true, nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true, nil
+	}
+	return "", false, nil
+}
+
+func (e *QueryEncoder) nestedKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if e.NestedFormat == NestedFormatDots {
+		return prefix + "." + name
+	}
+	return prefix + "[" + name + "]"
+}
+
+// queryFieldName returns the query name and options of a struct field.
+func queryFieldName(field reflect.StructField) (name string, opts queryTagOptions, skip bool) {
+	tag, ok := field.Tag.Lookup("url")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", opts, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		switch option {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "unix":
+			opts.unix = true
+		}
+	}
+	return parts[0], opts, false
+}
+
+// indirectValue dereferences pointers and interfaces, it returns the zero Value for nil.
+func indirectValue(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		if rv.Kind() == reflect.Ptr && rv.Type().Elem() != timeType && rv.Type().Implements(textMarshalerType) {
+			return rv
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func isQueryObject(rv reflect.Value) bool {
+	if rv.Kind() == reflect.Map {
+		return rv.Type().Key().Kind() == reflect.String
+	}
+	return rv.Kind() == reflect.Struct && rv.Type() != timeType
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	}
+	if rv.Type() == timeType {
+		return rv.Interface().(time.Time).IsZero()
+	}
+	return rv.IsZero()
+}
@@ -0,0 +1,111 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type queryAddress struct {
+	City string `url:"city"`
+	Zip  string `url:"zip,omitempty"`
+}
+
+type querySearch struct {
+	Name    string       `url:"name"`
+	Page    int          `url:"page,omitempty"`
+	ID      int64        `json:"id"`
+	Tags    []string     `url:"tags"`
+	Since   time.Time    `url:"since"`
+	Until   time.Time    `url:"until,unix"`
+	Address queryAddress `url:"address"`
+	Secret  string       `url:"-"`
+	Ptr     *string      `url:"ptr,omitempty"`
+}
+
+func TestQueryEncoder(t *testing.T) {
+	search := querySearch{
+		Name:    "go requests",
+		ID:      9007199254740993,
+		Tags:    []string{"a", "b"},
+		Since:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Until:   time.Unix(1700000000, 0),
+		Address: queryAddress{City: "Paris"},
+		Secret:  "s",
+	}
+	const rest = "&id=9007199254740993&name=go+requests&since=2023-01-02T03%3A04%3A05Z"
+	tests := []struct {
+		name    string
+		encoder *QueryEncoder
+		want    string
+	}{
+		{
+			name:    "repeat",
+			encoder: &QueryEncoder{},
+			want:    "address%5Bcity%5D=Paris" + rest + "&tags=a&tags=b&until=1700000000",
+		},
+		{
+			name:    "brackets",
+			encoder: &QueryEncoder{ArrayFormat: ArrayFormatBrackets, NestedFormat: NestedFormatDots},
+			want:    "address.city=Paris" + rest + "&tags%5B%5D=a&tags%5B%5D=b&until=1700000000",
+		},
+		{
+			name:    "comma",
+			encoder: &QueryEncoder{ArrayFormat: ArrayFormatComma},
+			want:    "address%5Bcity%5D=Paris" + rest + "&tags=a%2Cb&until=1700000000",
+		},
+		{
+			name:    "indexed",
+			encoder: &QueryEncoder{ArrayFormat: ArrayFormatIndexed},
+			want:    "address%5Bcity%5D=Paris" + rest + "&tags%5B0%5D=a&tags%5B1%5D=b&until=1700000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encoder.Encode(&search)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := NewQueryEncoder().Encode(42)
+	require.ErrorIs(t, err, ErrUnsupportedQueryType)
+}
+
+func TestGetQueryStruct(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	client := New().WithQueryStruct(map[string]any{"v": 1})
+	body, err := client.GetBytes(context.Background(), server.URL, queryAddress{City: "Paris", Zip: "75001"})
+	require.NoError(t, err)
+	require.Equal(t, "city=Paris&v=1&zip=75001", string(body))
+
+	body, err = client.NewRequest(context.Background(), http.MethodGet, server.URL, nil).
+		WithQueryStruct(queryAddress{City: "Lyon"}).
+		DoBytes()
+	require.NoError(t, err)
+	require.Equal(t, "city=Lyon&v=1", string(body))
+
+	// Raw query strings are appended as they are.
+	body, err = client.GetBytes(context.Background(), server.URL, "z=1&a=2")
+	require.NoError(t, err)
+	require.Equal(t, "v=1&z=1&a=2", string(body))
+
+	// Parameters whose keys are already in the URI or in the client query are added, not dropped.
+	body, err = client.GetBytes(context.Background(), server.URL+"/x?page=1", url.Values{"page": {"2"}, "q": {"a"}, "v": {"2"}})
+	require.NoError(t, err)
+	require.Equal(t, "page=1&page=2&q=a&v=1&v=2", string(body))
+	body, err = client.NewRequest(context.Background(), http.MethodGet, server.URL+"/x?page=1", map[string]string{"page": "2"}).
+		SetQueryMergeMode(QueryMergeRaw).
+		DoBytes()
+	require.NoError(t, err)
+	require.Equal(t, "page=1&page=2", string(body))
+}
@@ -0,0 +1,118 @@
+package requests
+
+import (
+	"net/url"
+	"strings"
+)
+
+// QueryMergeMode is how query values are merged with the query string of a URI.
+//
+// Every value of a repeated key is kept, and the query string of the URI is never re-encoded:
+// its parameters keep their bytes and order, and the new ones are added after them.
+type QueryMergeMode int
+
+const (
+	// QueryMergeReplace replaces every value of the keys which are already in the URI.
+	QueryMergeReplace QueryMergeMode = iota
+	// QueryMergeAdd adds the values after the values of the same keys in the URI.
+	QueryMergeAdd
+	// QueryMergeKeep keeps the keys which are already in the URI and adds the others.
+	QueryMergeKeep
+	// QueryMergeRaw sends the query string of the URI as it is, without the client and request query values,
+	// e.g. for signed URLs.
+	QueryMergeRaw
+)
+
+// SetQueryMergeMode sets how the client and request query values are merged with the query string
+// of the request URIs, QueryMergeKeep by default so the parameters written in a URI are never overridden.
+func (c *Client) SetQueryMergeMode(mode QueryMergeMode) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.queryMergeMode = mode
+	return c
+}
+
+// SetQueryMergeMode overrides the client query merge mode for this request.
+//
+//	// The presigned query string is sent byte for byte.
+//	client.NewRequest(ctx, http.MethodGet, presignedURL, nil).SetQueryMergeMode(requests.QueryMergeRaw).Do()
+func (r *ClientRequest) SetQueryMergeMode(mode QueryMergeMode) *ClientRequest {
+	r.queryMergeMode = mode
+	return r
+}
+
+// MergeValues merges the values of query into dst with mode and returns dst,
+// a nil dst is allocated. The values of dst are kept with QueryMergeRaw.
+func MergeValues(dst url.Values, mode QueryMergeMode, query ...url.Values) url.Values {
+	if dst == nil {
+		dst = make(url.Values)
+	}
+	if mode == QueryMergeRaw {
+		return dst
+	}
+	for _, values := range query {
+		for k, vs := range values {
+			switch mode {
+			case QueryMergeAdd:
+				dst[k] = append(dst[k], vs...)
+			case QueryMergeKeep:
+				if _, ok := dst[k]; !ok {
+					dst[k] = append([]string(nil), vs...)
+				}
+			default:
+				dst[k] = append([]string(nil), vs...)
+			}
+		}
+	}
+	return dst
+}
+
+// mergeRawQuery merges query into the query string rawQuery with mode,
+// the parameters of rawQuery which are kept are not re-encoded.
+func mergeRawQuery(rawQuery string, mode QueryMergeMode, query url.Values) string {
+	return mergeRawQueryAdd(rawQuery, mode, query, nil)
+}
+
+// mergeRawQueryAdd is mergeRawQuery which also adds the values of add after the values of the same keys
+// whatever mode, so parameters passed explicitly are never dropped. They are encoded together with query.
+func mergeRawQueryAdd(rawQuery string, mode QueryMergeMode, query, add url.Values) string {
+	if mode == QueryMergeRaw {
+		query = nil
+	}
+	if len(query) == 0 && len(add) == 0 {
+		return rawQuery
+	}
+	existing := make(map[string]bool)
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key := pair
+		if i := strings.IndexByte(key, '='); i >= 0 {
+			key = key[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if _, ok := query[key]; ok && mode == QueryMergeReplace {
+			continue
+		}
+		existing[key] = true
+		pairs = append(pairs, pair)
+	}
+	added := make(url.Values, len(query)+len(add))
+	for k, vs := range query {
+		if mode == QueryMergeKeep && existing[k] {
+			continue
+		}
+		added[k] = vs
+	}
+	for k, vs := range add {
+		added[k] = append(append([]string(nil), added[k]...), vs...)
+	}
+	if encoded := added.Encode(); encoded != "" {
+		pairs = append(pairs, encoded)
+	}
+	return strings.Join(pairs, "&")
+}
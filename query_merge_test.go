@@ -0,0 +1,73 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeRawQuery(t *testing.T) {
+	query := url.Values{"tag": {"c", "d"}, "page": {"2"}}
+	for _, tt := range []struct {
+		name     string
+		rawQuery string
+		mode     QueryMergeMode
+		want     string
+	}{
+		{"replace", "tag=a&x=%7e&tag=b", QueryMergeReplace, "x=%7e&page=2&tag=c&tag=d"},
+		{"add", "tag=a&x=%7e&tag=b", QueryMergeAdd, "tag=a&x=%7e&tag=b&page=2&tag=c&tag=d"},
+		{"keep", "tag=a&x=%7e&tag=b", QueryMergeKeep, "tag=a&x=%7e&tag=b&page=2"},
+		{"raw", "tag=a&x=%7e&tag=b", QueryMergeRaw, "tag=a&x=%7e&tag=b"},
+		{"empty", "", QueryMergeKeep, "page=2&tag=c&tag=d"},
+		{"escaped key", "ta%67=a", QueryMergeReplace, "page=2&tag=c&tag=d"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, mergeRawQuery(tt.rawQuery, tt.mode, query))
+		})
+	}
+	require.Equal(t, "b=2&a=1", mergeRawQuery("b=2&a=1", QueryMergeReplace, nil))
+}
+
+func TestMergeValues(t *testing.T) {
+	dst := url.Values{"tag": {"a"}}
+	require.Equal(t, url.Values{"tag": {"a", "b", "c"}}, MergeValues(dst, QueryMergeAdd, url.Values{"tag": {"b", "c"}}))
+	require.Equal(t, url.Values{"tag": {"a", "b", "c"}, "x": {"1"}}, MergeValues(dst, QueryMergeKeep, url.Values{"tag": {"d"}, "x": {"1"}}))
+	require.Equal(t, url.Values{"tag": {"e", "f"}, "x": {"1"}}, MergeValues(dst, QueryMergeReplace, url.Values{"tag": {"e", "f"}}))
+	require.Equal(t, url.Values{"tag": {"a", "b"}}, UrlValues(url.Values{"tag": {"x"}}, url.Values{"tag": {"a", "b"}}))
+	require.Equal(t, "http://127.0.0.1/?tag=a&tag=b", URIQuery("http://127.0.0.1/", url.Values{"tag": {"a", "b"}}).String())
+}
+
+func TestClientQueryMergeMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetQuery(url.Values{"page": {"1"}, "limit": {"10"}})
+	for _, tt := range []struct {
+		name     string
+		override bool
+		mode     QueryMergeMode
+		uri      string
+		want     string
+	}{
+		{"keep by default", false, 0, "/items?page=3&tag=a&tag=b", "page=3&tag=a&tag=b&limit=10"},
+		{"replace", true, QueryMergeReplace, "/items?page=3&tag=a", "tag=a&limit=10&page=1"},
+		{"add", true, QueryMergeAdd, "/items?page=3", "page=3&limit=10&page=1"},
+		{"raw", true, QueryMergeRaw, "/items?X-Sig=a%2Fb&b=1&a=2", "X-Sig=a%2Fb&b=1&a=2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := client.NewRequest(context.Background(), http.MethodGet, tt.uri, nil)
+			if tt.override {
+				r.SetQueryMergeMode(tt.mode)
+			}
+			response, err := r.Do()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, response.ReadAllString())
+		})
+	}
+}
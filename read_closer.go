@@ -1,6 +1,9 @@
 package requests
 
-import "io"
+import (
+	"io"
+	"sync"
+)
 
 // ReadCloser implements the io.ReadCloser interface
 // which is used for reading request body content multiple times.
@@ -35,3 +38,53 @@ func (b *ReadCloser) Read(p []byte) (n int, err error) {
 func (b *ReadCloser) Close() error {
 	return nil
 }
+
+// pipeReadCloser is a body produced by write through an io.Pipe.
+// write starts on the first Read, so nothing is done when the body is never sent,
+// source is closed when the body is closed before that.
+type pipeReadCloser struct {
+	write  func(pw *io.PipeWriter)
+	source io.Closer
+
+	once   sync.Once
+	mu     sync.Mutex
+	pr     *io.PipeReader
+	closed bool
+}
+
+func (r *pipeReadCloser) start() {
+	r.once.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		pr, pw := io.Pipe()
+		r.pr = pr
+		if r.closed {
+			_ = pr.Close()
+			return
+		}
+		go r.write(pw)
+	})
+}
+
+// Read implements the io.ReadCloser interface.
+func (r *pipeReadCloser) Read(p []byte) (int, error) {
+	r.start()
+	return r.pr.Read(p)
+}
+
+// Close implements the io.ReadCloser interface.
+func (r *pipeReadCloser) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.pr != nil {
+		return r.pr.Close()
+	}
+	if r.source != nil {
+		return r.source.Close()
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	requestContextKey CtxKeyString = "__request_key"
+)
+
+// ClientRequest is a single execution of an HTTP call made by a Client.
+// It is not named Request as the package function Request already sends a one-off request.
+//
+// The client configuration is used as immutable defaults,
+// everything that changes while the call is executed lives on the ClientRequest,
+// so one Client can be shared by many goroutines.
+//
+//	response, err := client.NewRequest(ctx, http.MethodPost, "/users", user).
+//		AsJson().
+//		WithHeader("X-Request-Id", id).
+//		Do()
+type ClientRequest struct {
+	Method string
+	URI    string
+	Body   any
+	// Header, Query and Cookie are applied on top of the client defaults.
+	Header http.Header
+	Query  url.Values
+	Cookie Cookie
+	// PathParams and RawPathParams replace `{name}` placeholders of URI,
+	// on top of the client path parameters.
+	PathParams    map[string]string
+	RawPathParams map[string]string
+
+	// RawRequest is the underlying http.Request, available once the request has been prepared.
+	RawRequest *http.Request
+	// Attempt is the number of the current attempt, including retries and hedged attempts.
+	Attempt int
+
+	client                 *Client
+	ctx                    context.Context
+	retryPolicy            RetryPolicy
+	hedgePolicy            *HedgePolicy
+	queryStructs           []any
+	removedHeaders         []string
+	removedCookies         []string
+	queryMergeMode         QueryMergeMode
+	uploadProgress         ProgressFunc
+	downloadProgress       ProgressFunc
+	compression            *Compression
+	signers                []Signer
+	middlewares            []MiddlewareFunc
+	beforeRequestCallbacks []ClientCallback
+	afterRequestCallbacks  []RequestCallback
+	responseCallbacks      []ResponseCallback
+	successHooks           []SuccessHook
+	errorHooks             []ErrorHook
+	panicHooks             []ErrorHook
+	attemptHooks           []AttemptHook
+	headerKVs              []KVCallback
+	queryKVs               []KVCallback
+	forceContentType       string
+	loadBalancer           *LoadBalancer
+	endpoint               *endpoint
+	endpointPending        bool
+	relativeURI            string
+	scheme                 string
+	baseURLJoin            bool
+	attempts               int
+	hedged                 bool
+	trace                  bool
+	traceContext           *traceContext
+	traceHooks             *httptrace.ClientTrace // the trace hooks in the context of the prepared request
+	startTime              time.Time
+}
+
+// NewRequest creates a ClientRequest which uses the client configuration as defaults.
+func (c *Client) NewRequest(ctx context.Context, method, uri string, body any) *ClientRequest {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return &ClientRequest{
+		Method:                 strings.ToUpper(method),
+		URI:                    uri,
+		Body:                   body,
+		Header:                 make(http.Header),
+		Query:                  make(url.Values),
+		Cookie:                 make(Cookie),
+		PathParams:             make(map[string]string),
+		RawPathParams:          make(map[string]string),
+		client:                 c,
+		ctx:                    ctx,
+		retryPolicy:            c.retryPolicy,
+		hedgePolicy:            c.hedgePolicy,
+		queryMergeMode:         c.queryMergeMode,
+		uploadProgress:         c.uploadProgress,
+		downloadProgress:       c.downloadProgress,
+		compression:            c.compression,
+		signers:                append([]Signer(nil), c.signers...),
+		middlewares:            append([]MiddlewareFunc(nil), c.middlewares...),
+		beforeRequestCallbacks: append([]ClientCallback(nil), c.beforeRequestCallbacks...),
+		afterRequestCallbacks:  append([]RequestCallback(nil), c.afterRequestCallbacks...),
+		responseCallbacks:      append([]ResponseCallback(nil), c.responseCallbacks...),
+		successHooks:           append([]SuccessHook(nil), c.successHooks...),
+		errorHooks:             append([]ErrorHook(nil), c.errorHooks...),
+		panicHooks:             append([]ErrorHook(nil), c.panicHooks...),
+		attemptHooks:           append([]AttemptHook(nil), c.attemptHooks...),
+		headerKVs:              append([]KVCallback(nil), c.HeaderKVs...),
+		queryKVs:               append([]KVCallback(nil), c.QueryKVs...),
+		forceContentType:       c.forceContentType,
+		trace:                  c.trace,
+	}
+}
+
+// requestFromContext returns the ClientRequest which is executed with ctx.
+func requestFromContext(ctx context.Context) *ClientRequest {
+	if r, ok := ctx.Value(requestContextKey).(*ClientRequest); ok {
+		return r
+	}
+	return nil
+}
+
+// Client returns the client which created the request.
+func (r *ClientRequest) Client() *Client {
+	return r.client
+}
+
+// Context returns the context of the request.
+func (r *ClientRequest) Context() context.Context {
+	return r.ctx
+}
+
+// WithContext sets the context of the request.
+func (r *ClientRequest) WithContext(ctx context.Context) *ClientRequest {
+	if ctx != nil {
+		r.ctx = ctx
+	}
+	return r
+}
+
+// WithHeader sets a header for this request only, overriding the client header.
+func (r *ClientRequest) WithHeader(k, v string) *ClientRequest {
+	r.Header.Set(k, v)
+	return r
+}
+
+// WithoutHeader removes the header k of the client defaults for this request only,
+// a header set on the request afterwards is sent again.
+//
+//	client.NewRequest(ctx, http.MethodGet, "/public", nil).WithoutHeader("Authorization").Do()
+//
+// Without a User-Agent, no User-Agent header is sent rather than the Go default one.
+func (r *ClientRequest) WithoutHeader(k string) *ClientRequest {
+	r.Header.Del(k)
+	r.removedHeaders = append(r.removedHeaders, k)
+	return r
+}
+
+// WithHeaderMap sets multiple headers for this request only.
+func (r *ClientRequest) WithHeaderMap(headers map[string]string) *ClientRequest {
+	for k, v := range headers {
+		r.WithHeader(k, v)
+	}
+	return r
+}
+
+// WithContentType sets the content type for this request only.
+func (r *ClientRequest) WithContentType(contentType string) *ClientRequest {
+	return r.WithHeader(HttpHeaderContentType, contentType)
+}
+
+// AsForm sets the content type as "application/x-www-form-urlencoded" for this request only.
+func (r *ClientRequest) AsForm() *ClientRequest {
+	return r.WithContentType(HttpHeaderContentTypeForm)
+}
+
+// AsJson sets the content type as "application/json" for this request only.
+func (r *ClientRequest) AsJson() *ClientRequest {
+	return r.WithContentType(HttpHeaderContentTypeJson)
+}
+
+// AsXml sets the content type as "application/xml" for this request only.
+func (r *ClientRequest) AsXml() *ClientRequest {
+	return r.WithContentType(HttpHeaderContentTypeXml)
+}
+
+// WithQuery sets a query parameter for this request only.
+func (r *ClientRequest) WithQuery(k, v string) *ClientRequest {
+	r.Query.Set(k, v)
+	return r
+}
+
+// WithCookie sets a cookie for this request only.
+func (r *ClientRequest) WithCookie(k, v string) *ClientRequest {
+	r.Cookie.Set(k, v)
+	return r
+}
+
+// WithoutCookie removes the cookie k of the client defaults for this request only.
+func (r *ClientRequest) WithoutCookie(k string) *ClientRequest {
+	r.Cookie.Del(k)
+	r.removedCookies = append(r.removedCookies, k)
+	return r
+}
+
+// SetRetryPolicy overrides the client retry policy for this request.
+func (r *ClientRequest) SetRetryPolicy(policy RetryPolicy) *ClientRequest {
+	r.retryPolicy = policy
+	return r
+}
+
+// EnableTrace enables tracing for this request.
+func (r *ClientRequest) EnableTrace() *ClientRequest {
+	r.trace = true
+	return r
+}
+
+// TraceInfo returns the trace information of the request,
+// it's only populated when trace is enabled.
+func (r *ClientRequest) TraceInfo() TraceInfo {
+	ti := TraceInfo{}
+	if r.traceContext != nil {
+		ti = r.traceContext.Get()
+	}
+	ti.RequestAttempt = r.Attempt
+	ti.Hedged = r.hedged
+	return ti
+}
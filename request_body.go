@@ -0,0 +1,138 @@
+package requests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrBodyNotReplayable = errors.New("request body cannot be replayed")
+)
+
+// setRequestBody sets body as the body of request together with its ContentLength
+// and a GetBody which returns a fresh copy of the body,
+// so it can be sent again on retries and 307/308 redirects.
+//
+// In-memory bodies and io.ReadSeeker, e.g. *os.File, are replayable and have a known length,
+// any other io.Reader is sent once with an unknown length, using chunked transfer encoding.
+// Replayable bodies are never closed, so a file stays owned by the caller.
+func setRequestBody(request *http.Request, body io.Reader) error {
+	request.Body, request.GetBody, request.ContentLength = nil, nil, 0
+	if body == nil {
+		return nil
+	}
+	switch v := body.(type) {
+	case *bytes.Buffer:
+		buf := v.Bytes()
+		request.ContentLength = int64(len(buf))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf)), nil
+		}
+	case *bytes.Reader:
+		snapshot := *v
+		request.ContentLength = int64(v.Len())
+		request.GetBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return io.NopCloser(&r), nil
+		}
+	case *strings.Reader:
+		snapshot := *v
+		request.ContentLength = int64(v.Len())
+		request.GetBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return io.NopCloser(&r), nil
+		}
+	case io.ReadSeeker:
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			request.ContentLength = -1
+			break
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf(`seek request body failed: %w`, err)
+		}
+		if _, err = v.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf(`seek request body failed: %w`, err)
+		}
+		request.ContentLength = end - offset
+		request.GetBody = func() (io.ReadCloser, error) {
+			if _, err := v.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(v), nil
+		}
+	default:
+		request.ContentLength = -1
+	}
+	if request.GetBody != nil {
+		if request.ContentLength == 0 {
+			request.Body = http.NoBody
+			request.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+			return nil
+		}
+		var err error
+		request.Body, err = request.GetBody()
+		return err
+	}
+	if rc, ok := body.(io.ReadCloser); ok {
+		request.Body = rc
+	} else {
+		request.Body = io.NopCloser(body)
+	}
+	return nil
+}
+
+// streamBody returns body when it is an io.Reader which is streamed as it is,
+// a *bytes.Buffer is encoded like a string for compatibility.
+func streamBody(body any) (io.Reader, bool) {
+	switch v := body.(type) {
+	case *bytes.Buffer:
+		return nil, false
+	case io.Reader:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, false
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// rewindBody resets the body of request before it is sent again.
+func rewindBody(request *http.Request) error {
+	if !hasBody(request) {
+		return nil
+	}
+	if request.GetBody == nil {
+		return ErrBodyNotReplayable
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return fmt.Errorf(`%w: %v`, ErrBodyNotReplayable, err)
+	}
+	request.Body = body
+	return nil
+}
+
+func hasBody(request *http.Request) bool {
+	return request.Body != nil && request.Body != http.NoBody && request.ContentLength != 0
+}
+
+// checkRedirectBody reports an error when the server asked to resend the request
+// with its body but the body cannot be replayed, as net/http then returns the
+// redirect response itself instead of following it.
+func checkRedirectBody(request *http.Request, response *http.Response) error {
+	if response.StatusCode != http.StatusTemporaryRedirect && response.StatusCode != http.StatusPermanentRedirect {
+		return nil
+	}
+	location := response.Header.Get("Location")
+	if location == "" || !hasBody(request) || request.GetBody != nil {
+		return nil
+	}
+	return fmt.Errorf(`redirect to "%s": %w`, location, ErrBodyNotReplayable)
+}
@@ -0,0 +1,135 @@
+package requests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryReplaysRequestBody(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		attempt := len(bodies)
+		mu.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New().SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond))
+	response, err := client.AsJson().Post(context.Background(), server.URL, map[string]string{"k": "v"})
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, []string{`{"k":"v"}`, `{"k":"v"}`}, bodies)
+}
+
+func TestRedirectReplaysRequestBody(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	response, err := New().Post(context.Background(), server.URL+"/old", "k=v")
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "k=v", body)
+}
+
+func TestSetRequestBodyReadSeeker(t *testing.T) {
+	f, err := os.Open("./.testdata/github.png")
+	require.NoError(t, err)
+	defer f.Close()
+	stat, err := f.Stat()
+	require.NoError(t, err)
+
+	request, _ := http.NewRequest(http.MethodPut, "http://127.0.0.1", nil)
+	require.NoError(t, setRequestBody(request, f))
+	require.Equal(t, stat.Size(), request.ContentLength)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, rewindBody(request))
+		b, err := io.ReadAll(request.Body)
+		require.NoError(t, err)
+		require.Equal(t, stat.Size(), int64(len(b)))
+	}
+}
+
+func TestRewindBodyNotReplayable(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1", nil)
+	require.NoError(t, setRequestBody(request, io.MultiReader(strings.NewReader("k=v"))))
+	require.Equal(t, int64(-1), request.ContentLength)
+	require.ErrorIs(t, rewindBody(request), ErrBodyNotReplayable)
+}
+
+func TestStreamRequestBody(t *testing.T) {
+	type received struct {
+		body          string
+		contentLength int64
+		chunked       bool
+	}
+	var (
+		mu    sync.Mutex
+		calls []received
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		calls = append(calls, received{string(b), r.ContentLength, len(r.TransferEncoding) > 0})
+		attempt := len(calls)
+		mu.Unlock()
+		if attempt%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	client := New().SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus))
+
+	file, err := os.CreateTemp(t.TempDir(), "body")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString("file body")
+	require.NoError(t, err)
+	_, err = file.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	response, err := client.Put(context.Background(), server.URL, file)
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, []received{{"file body", 9, false}, {"file body", 9, false}}, calls)
+
+	// A pipe is sent chunked once, it is not retried as it cannot be replayed.
+	calls = nil
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("pipe "))
+		_, _ = pw.Write([]byte("body"))
+		_ = pw.Close()
+	}()
+	response, err = client.Post(context.Background(), server.URL, pr)
+	require.NoError(t, err)
+	_ = response.Close()
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	require.Equal(t, []received{{"pipe body", -1, true}}, calls)
+}
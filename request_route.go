@@ -18,6 +18,7 @@ type IRoute interface {
 type Route struct {
 	Method, Uri     string
 	Header, Cookies map[string]string
+	PathParams      map[string]string
 	TlsConfig       *tls.Config
 	Body, D         any
 }
@@ -42,6 +43,10 @@ func (r *Route) GetCookies() map[string]string {
 	return r.Cookies
 }
 
+func (r *Route) GetPathParams() map[string]string {
+	return r.PathParams
+}
+
 func (r *Route) GetBody() any {
 	return r.Body
 }
@@ -52,8 +57,9 @@ func (r *Route) GetD() any {
 
 // RequestRoute
 // route := &requests.Route{
-//		Uri:    "https://api.github.com/users/github",
+//		Uri:    "https://api.github.com/users/{user}",
 //		Method: http.MethodGet,
+//		PathParams: map[string]string{"user": "github"},
 //		D:      &.tests.GitHubUser{},
 //	}
 //	_ = requests.RequestRoute(route)
@@ -63,6 +69,9 @@ func RequestRoute(route IRoute) error {
 	client.WithHeaderMap(route.GetHeader())
 	client.SetTLSConfig(route.GetTlsConfig())
 	client.WithCookieMap(route.GetCookies())
+	if p, ok := route.(interface{ GetPathParams() map[string]string }); ok {
+		client.SetPathParams(p.GetPathParams())
+	}
 	request, err := client.DoRequest(context.Background(), route.GetMethod(), route.GetUri(), route.GetBody())
 	if err != nil {
 		return err
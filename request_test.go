@@ -0,0 +1,128 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("X-Id"), r.URL.Query().Get("id"), r.Header.Get(HttpHeaderCookie))
+	}))
+	defer server.Close()
+
+	client := New().SetRetry(0, 0).EnableTrace().EnableDebug().SetWriter(io.Discard).WithHeader("X-Default", "1")
+	client.SetLogger(NewLogger(log.New(io.Discard, "", 0), ""))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			response, err := client.NewRequest(context.Background(), http.MethodGet, server.URL, nil).
+				WithHeader("X-Id", id).
+				WithQuery("id", id).
+				WithCookie("id", id).
+				Do()
+			require.NoError(t, err)
+			defer response.Close()
+			require.Equal(t, id+"|"+id+"|id="+id, response.ReadAllString())
+			require.Equal(t, 1, response.TraceInfo().RequestAttempt)
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, "", client.Header.Get("X-Id"))
+	require.Equal(t, "", client.Header.Get(HttpHeaderCookie))
+	require.Equal(t, "1", client.Header.Get("X-Default"))
+}
+
+func TestClientConfigureWhileRequesting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			response, err := client.Get(context.Background(), server.URL, nil)
+			require.NoError(t, err)
+			_ = response.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			client.Use(func(c *Client, r *http.Request) (*Response, error) { return c.Next(r) })
+			client.WithHeaderKV(func() (string, string) { return "X-Kv", "1" })
+			client.WithQueryKV(func() (string, string) { return "kv", "1" })
+			client.OnBeforeRequest(func(c *Client) error { return nil })
+			client.OnResponse(func(c *Client, request *http.Request, response *Response) error { return nil })
+			client.OnSuccess(func(c *Client, response *Response) {})
+			client.OnError(func(c *Client, request *http.Request, err error) {})
+			client.OnPanic(func(c *Client, request *http.Request, err error) {})
+			client.OnAttempt(func(c *Client, request *http.Request) (func(*Response, error), error) { return nil, nil })
+			client.SetRetryPolicy(NoRetryPolicy()).SetHedgePolicy(nil).EnableTrace()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPostJsonDoesNotChangeClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(HttpHeaderContentType)))
+	}))
+	defer server.Close()
+
+	client := New()
+	response, err := client.PostJson(context.Background(), server.URL, map[string]string{"k": "v"})
+	require.NoError(t, err)
+	defer response.Close()
+	require.Equal(t, HttpHeaderContentTypeJson, response.ReadAllString())
+	require.Equal(t, "", client.Header.Get(HttpHeaderContentType))
+}
+
+func TestClientRequestHeaderLayers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s|%s|%s|%s|%q", r.Header.Get(HttpHeaderAuthorization), r.Header.Get("X-Env"),
+			r.Header.Get("X-Middleware"), r.Header.Get(HttpHeaderCookie), r.Header.Values(HttpHeaderUserAgent))
+	}))
+	defer server.Close()
+
+	client := New().WithToken("secret").WithHeader("X-Env", "prod").WithCookie("session", "1").WithCookie("lang", "en")
+	client.Use(func(c *Client, r *http.Request) (*Response, error) {
+		r.Header.Set("X-Middleware", "1")
+		r.Header.Set("X-Env", r.Header.Get("X-Env")+"+mw")
+		return c.Next(r)
+	})
+
+	response, err := client.NewRequest(context.Background(), http.MethodGet, server.URL, nil).
+		WithoutHeader(HttpHeaderAuthorization).
+		WithoutHeader(HttpHeaderUserAgent).
+		WithHeader("X-Env", "staging").
+		WithHeader(HttpHeaderCookie, "raw=1").
+		WithoutCookie("session").
+		Do()
+	require.NoError(t, err)
+	require.Equal(t, `|staging+mw|1|raw=1; lang=en|[]`, response.ReadAllString())
+
+	// Removing a header then setting it sends the request value.
+	response, err = client.NewRequest(context.Background(), http.MethodGet, server.URL, nil).
+		WithoutHeader("X-Env").
+		WithHeader("X-Env", "dev").
+		Do()
+	require.NoError(t, err)
+	require.Equal(t, `Bearer secret|dev+mw|1|lang=en; session=1|["`+defaultClientAgent+`"]`, response.ReadAllString())
+
+	require.Equal(t, "prod", client.Header.Get("X-Env"))
+	require.Equal(t, "", client.Header.Get("X-Middleware"))
+	require.Equal(t, "", client.Header.Get(HttpHeaderCookie))
+	require.True(t, client.Cookie.Has("session"))
+}
@@ -10,6 +10,7 @@ type Response struct {
 	*http.Response               // Response is the underlying http.Response object of certain request.
 	request        *http.Request // Request is the underlying http.Request object of certain request.
 	client         *Client
+	req            *ClientRequest // req is the execution which produced the response.
 }
 
 // Close closes the response when it will never be used.
@@ -19,11 +20,15 @@ func (r *Response) Close() error {
 	}
 	r.client = nil
 	r.request = nil
+	r.req = nil
 	return r.Response.Body.Close()
 }
 
 func (r *Response) TraceInfo() TraceInfo {
-	return r.client.getTraceInfo()
+	if r.req == nil {
+		return TraceInfo{}
+	}
+	return r.req.TraceInfo()
 }
 
 func (r *Response) GetCookie() Cookie {
@@ -100,9 +105,17 @@ func (r *Response) ContentType() string {
 	return r.Response.Header.Get(HttpHeaderContentType)
 }
 
-// Unmarshal content into object from JSON or XML
+// Unmarshal content into object with the codec of the response Content-Type,
+// or of the content type forced with ForceContentType.
 func (r *Response) Unmarshal(d any) error {
-	return r.client.Unmarshal(r.ContentType(), r.ReadAll(), d)
+	if d == nil {
+		return nil
+	}
+	contentType := r.ContentType()
+	if r.req != nil && r.req.forceContentType != "" {
+		contentType = r.req.forceContentType
+	}
+	return r.client.Unmarshal(contentType, r.ReadAll(), d)
 }
 
 // IsSuccess method returns true if HTTP status `code >= 200 and <= 299` otherwise false.
@@ -0,0 +1,270 @@
+package requests
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HttpHeaderSignature = `X-Signature`
+	HttpHeaderTimestamp = `X-Timestamp`
+	HttpHeaderNonce     = `X-Nonce`
+
+	// DefaultSignatureTemplate is the string to sign of an HMACSigner without a Template.
+	DefaultSignatureTemplate = "{method}\n{path}\n{query}\n{headers}\n{timestamp}\n{nonce}\n{body_hash}"
+)
+
+// Signer signs requests, it runs for every attempt of a request right before it is sent,
+// after the middlewares, so retries and hedged attempts are signed again.
+type Signer interface {
+	Sign(request *http.Request) error
+}
+
+// SignerFunc is a Signer function.
+type SignerFunc func(request *http.Request) error
+
+func (f SignerFunc) Sign(request *http.Request) error {
+	return f(request)
+}
+
+// WithSigner adds signers which sign every attempt of the requests of the client, in order.
+func (c *Client) WithSigner(signers ...Signer) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.signers = append(c.signers, signers...)
+	return c
+}
+
+// WithSigner adds signers for this request, after the client signers.
+func (r *ClientRequest) WithSigner(signers ...Signer) *ClientRequest {
+	r.signers = append(r.signers, signers...)
+	return r
+}
+
+// sign runs the signers of r on request.
+func (r *ClientRequest) sign(request *http.Request) error {
+	for _, signer := range r.signers {
+		if err := signer.Sign(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SignatureEncoding is the text encoding of signatures and body hashes.
+type SignatureEncoding int
+
+const (
+	SignatureHex SignatureEncoding = iota
+	SignatureBase64
+)
+
+func (e SignatureEncoding) encode(b []byte) string {
+	if e == SignatureBase64 {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return hex.EncodeToString(b)
+}
+
+// HMACSigner signs requests with an HMAC of a canonical string built from Template.
+//
+// The placeholders of Template are replaced by:
+//
+//	{method}     the request method
+//	{host}       the request host
+//	{path}       the escaped path
+//	{query}      the query parameters sorted by key and value, escaped with QueryEscape
+//	{headers}    the SignedHeaders as lower cased "name:value" lines
+//	{timestamp}  the timestamp, also sent in TimestampHeader
+//	{nonce}      the nonce, also sent in NonceHeader
+//	{body_hash}  the encoded hash of the body with Hash
+//
+// For example:
+//
+//	signer := requests.NewHMACSigner("secret")
+//	signer.Hash = sha1.New
+//	signer.Encoding = requests.SignatureBase64
+//	signer.SignedHeaders = []string{"Content-Type"}
+//	signer.SignaturePrefix = "HMAC-SHA1 "
+//	client.WithSigner(signer)
+type HMACSigner struct {
+	Key []byte
+	// Hash is the hash function of the HMAC and of the body hash, sha256.New when nil.
+	Hash     func() hash.Hash
+	Encoding SignatureEncoding
+	// Template is the string to sign, DefaultSignatureTemplate when empty.
+	Template      string
+	SignedHeaders []string
+	// SignatureHeader receives SignaturePrefix followed by the signature, X-Signature when empty.
+	SignatureHeader string
+	SignaturePrefix string
+	// TimestampHeader and NonceHeader are not sent when empty.
+	TimestampHeader string
+	NonceHeader     string
+	// BodyHashHeader receives the body hash when it is not empty.
+	BodyHashHeader string
+	// Timestamp returns the timestamp of a request, the Unix time in seconds when nil.
+	Timestamp func() string
+	// Nonce returns the nonce of a request, 16 random bytes in hex when nil.
+	Nonce func() string
+	// QueryEscape escapes the keys and values of {query}, url.QueryEscape when nil,
+	// which escapes spaces as "+". Servers which expect "%20" can use:
+	//
+	//	signer.QueryEscape = func(s string) string {
+	//		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	//	}
+	QueryEscape func(s string) string
+}
+
+// NewHMACSigner creates an HMAC-SHA256 signer with key which sends X-Signature, X-Timestamp and X-Nonce.
+func NewHMACSigner(key string) *HMACSigner {
+	return &HMACSigner{
+		Key:             []byte(key),
+		SignatureHeader: HttpHeaderSignature,
+		TimestampHeader: HttpHeaderTimestamp,
+		NonceHeader:     HttpHeaderNonce,
+	}
+}
+
+// Sign sets the signature headers of request.
+func (s *HMACSigner) Sign(request *http.Request) error {
+	timestamp, nonce := s.timestamp(), s.nonce()
+	if s.TimestampHeader != "" {
+		request.Header.Set(s.TimestampHeader, timestamp)
+	}
+	if s.NonceHeader != "" {
+		request.Header.Set(s.NonceHeader, nonce)
+	}
+	stringToSign, err := s.StringToSign(request, timestamp, nonce)
+	if err != nil {
+		return err
+	}
+	signatureHeader := s.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = HttpHeaderSignature
+	}
+	request.Header.Set(signatureHeader, s.SignaturePrefix+s.Encoding.encode(Hmac(s.hash(), s.Key, []byte(stringToSign))))
+	return nil
+}
+
+// StringToSign returns the string which is signed for request, e.g. to compare it with the one of a server.
+func (s *HMACSigner) StringToSign(request *http.Request, timestamp, nonce string) (string, error) {
+	template := s.Template
+	if template == "" {
+		template = DefaultSignatureTemplate
+	}
+	bodyHash := ""
+	if strings.Contains(template, "{body_hash}") || s.BodyHashHeader != "" {
+		h := s.hash()()
+		if err := copyBody(h, request); err != nil {
+			return "", fmt.Errorf("sign: %w", err)
+		}
+		bodyHash = s.Encoding.encode(h.Sum(nil))
+		if s.BodyHashHeader != "" {
+			request.Header.Set(s.BodyHashHeader, bodyHash)
+		}
+	}
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := make([]string, 0, len(s.SignedHeaders))
+	for _, k := range s.SignedHeaders {
+		value := request.Header.Get(k)
+		if strings.EqualFold(k, HttpHeaderHost) {
+			value = host
+		}
+		headers = append(headers, strings.ToLower(k)+":"+strings.TrimSpace(value))
+	}
+	return strings.NewReplacer(
+		"{method}", request.Method,
+		"{host}", host,
+		"{path}", request.URL.EscapedPath(),
+		"{query}", sortedQuery(request.URL.Query(), s.queryEscape()),
+		"{headers}", strings.Join(headers, "\n"),
+		"{timestamp}", timestamp,
+		"{nonce}", nonce,
+		"{body_hash}", bodyHash,
+	).Replace(template), nil
+}
+
+func (s *HMACSigner) hash() func() hash.Hash {
+	if s.Hash != nil {
+		return s.Hash
+	}
+	return sha256.New
+}
+
+func (s *HMACSigner) queryEscape() func(s string) string {
+	if s.QueryEscape != nil {
+		return s.QueryEscape
+	}
+	return url.QueryEscape
+}
+
+func (s *HMACSigner) timestamp() string {
+	if s.Timestamp != nil {
+		return s.Timestamp()
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+func (s *HMACSigner) nonce() string {
+	if s.Nonce != nil {
+		return s.Nonce()
+	}
+	return randomNonce()
+}
+
+// copyBody copies the body of request to w without consuming it.
+func copyBody(w io.Writer, request *http.Request) error {
+	if !hasBody(request) {
+		return nil
+	}
+	if request.GetBody == nil {
+		return ErrBodyNotReplayable
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// sortedQuery encodes query sorted by key and then value, with escape.
+func sortedQuery(query url.Values, escape func(s string) string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// randomNonce returns 16 random bytes in hex.
+func randomNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
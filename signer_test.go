@@ -0,0 +1,107 @@
+package requests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACSignerStringToSign(t *testing.T) {
+	signer := NewHMACSigner("secret")
+	signer.SignedHeaders = []string{"Host", "Content-Type"}
+	signer.Timestamp = func() string { return "1700000000" }
+	signer.Nonce = func() string { return "abc" }
+	request, err := http.NewRequest(http.MethodPost, "https://api.example.com/v1/orders?b=2&a=3&a=1", nil)
+	require.NoError(t, err)
+	require.NoError(t, setRequestBody(request, bytes.NewReader([]byte(`{"id":1}`))))
+	request.Header.Set(HttpHeaderContentType, HttpHeaderContentTypeJson)
+
+	stringToSign, err := signer.StringToSign(request, "1700000000", "abc")
+	require.NoError(t, err)
+	require.Equal(t, "POST\n/v1/orders\na=1&a=3&b=2\nhost:api.example.com\ncontent-type:application/json\n1700000000\nabc\n"+Sha256(`{"id":1}`), stringToSign)
+
+	require.NoError(t, signer.Sign(request))
+	require.Equal(t, HmacSha256("secret", stringToSign), request.Header.Get(HttpHeaderSignature))
+	require.Equal(t, "1700000000", request.Header.Get(HttpHeaderTimestamp))
+	require.Equal(t, "abc", request.Header.Get(HttpHeaderNonce))
+
+	// Spaces of the query are escaped as "+" unless QueryEscape escapes them otherwise.
+	request.URL.RawQuery = "q=a b"
+	signer.Template = "{query}"
+	stringToSign, err = signer.StringToSign(request, "", "")
+	require.NoError(t, err)
+	require.Equal(t, "q=a+b", stringToSign)
+	signer.QueryEscape = func(s string) string { return strings.ReplaceAll(url.QueryEscape(s), "+", "%20") }
+	stringToSign, err = signer.StringToSign(request, "", "")
+	require.NoError(t, err)
+	require.Equal(t, "q=a%20b", stringToSign)
+
+	signer = &HMACSigner{
+		Key:             []byte("secret"),
+		Hash:            sha1.New,
+		Encoding:        SignatureBase64,
+		Template:        "{method}&{path}&{body_hash}",
+		SignatureHeader: HttpHeaderAuthorization,
+		SignaturePrefix: "HMAC-SHA1 ",
+		BodyHashHeader:  "X-Body-Sha1",
+	}
+	require.NoError(t, signer.Sign(request))
+	sum := sha1.Sum([]byte(`{"id":1}`))
+	bodyHash := base64.StdEncoding.EncodeToString(sum[:])
+	require.Equal(t, bodyHash, request.Header.Get("X-Body-Sha1"))
+	require.Equal(t, "HMAC-SHA1 "+base64.StdEncoding.EncodeToString(Hmac(sha1.New, []byte("secret"), []byte("POST&/v1/orders&"+bodyHash))),
+		request.Header.Get(HttpHeaderAuthorization))
+}
+
+func TestClientWithSignerEveryAttempt(t *testing.T) {
+	signer := NewHMACSigner("secret")
+	signer.SignedHeaders = []string{"X-Tenant"}
+	var mu sync.Mutex
+	var nonces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		r.URL.Host = r.Host
+		stringToSign, err := signer.StringToSign(r, r.Header.Get(HttpHeaderTimestamp), r.Header.Get(HttpHeaderNonce))
+		require.NoError(t, err)
+		if r.Header.Get(HttpHeaderSignature) != HmacSha256("secret", stringToSign) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		nonces = append(nonces, r.Header.Get(HttpHeaderNonce))
+		if len(nonces) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, r.Header.Get("X-Tenant"))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).
+		SetRetryPolicy(FixedRetryPolicy(1, time.Millisecond, RetryOnTemporaryStatus)).
+		WithSigner(signer).
+		Use(func(c *Client, r *http.Request) (*Response, error) {
+			// Headers set by middlewares are signed.
+			r.Header.Set("X-Tenant", "acme")
+			return c.Next(r)
+		})
+	body, err := client.PostBytes(context.Background(), "/orders?id=1", "payload")
+	require.NoError(t, err)
+	require.Equal(t, "acme", string(body))
+	require.Len(t, nonces, 2)
+	require.NotEqual(t, nonces[0], nonces[1])
+}
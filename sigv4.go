@@ -0,0 +1,350 @@
+package requests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HttpHeaderAmzDate          = `X-Amz-Date`
+	HttpHeaderAmzContentSha256 = `X-Amz-Content-Sha256`
+	HttpHeaderAmzSecurityToken = `X-Amz-Security-Token`
+
+	// UnsignedPayload is the payload hash of requests whose body is not signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+	sigV4MaxExpires = 7 * 24 * time.Hour
+)
+
+var (
+	ErrSigV4 = errors.New("sigv4 signing failed")
+)
+
+// sigV4IgnoredHeaders are never signed, they are changed by proxies or by net/http.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":     true,
+	"user-agent":        true,
+	"x-amzn-trace-id":   true,
+	"expect":            true,
+	"connection":        true,
+	"keep-alive":        true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"content-length":    true,
+}
+
+// AWSCredentials are the credentials of a SigV4Signer.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials, sent as X-Amz-Security-Token.
+	SessionToken string
+}
+
+// AWSCredentialsProvider returns the credentials of every signed request,
+// providers of temporary credentials refresh them as they need.
+type AWSCredentialsProvider interface {
+	Credentials(ctx context.Context) (AWSCredentials, error)
+}
+
+// AWSCredentialsFunc is an AWSCredentialsProvider function.
+type AWSCredentialsFunc func(ctx context.Context) (AWSCredentials, error)
+
+func (f AWSCredentialsFunc) Credentials(ctx context.Context) (AWSCredentials, error) {
+	return f(ctx)
+}
+
+// StaticAWSCredentials returns a provider of fixed credentials.
+func StaticAWSCredentials(accessKeyID, secretAccessKey, sessionToken string) AWSCredentialsProvider {
+	credentials := AWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}
+	return AWSCredentialsFunc(func(ctx context.Context) (AWSCredentials, error) {
+		return credentials, nil
+	})
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4.
+//
+//	signer := requests.NewSigV4Signer(requests.StaticAWSCredentials(key, secret, ""), "us-east-1", "s3")
+//	client := requests.New().SetBaseURL("http://127.0.0.1:9000").WithSigV4(signer)
+//	url, err := signer.Presign(request, 15*time.Minute)
+type SigV4Signer struct {
+	Credentials AWSCredentialsProvider
+	Region      string
+	Service     string
+	// UnsignedPayload signs requests without hashing their body, e.g. for streamed uploads to S3 over TLS.
+	UnsignedPayload bool
+	// DisableURIPathEscaping signs the path as it is sent instead of removing its "." and ".." segments
+	// and escaping it twice, as S3 requires.
+	DisableURIPathEscaping bool
+	// DisableContentSHA256Header does not send the payload hash as X-Amz-Content-Sha256,
+	// which S3 requires and most other services ignore.
+	DisableContentSHA256Header bool
+}
+
+// NewSigV4Signer creates a SigV4Signer for region and service, with the S3 settings for "s3".
+func NewSigV4Signer(credentials AWSCredentialsProvider, region, service string) *SigV4Signer {
+	return &SigV4Signer{Credentials: credentials, Region: region, Service: service, DisableURIPathEscaping: service == "s3"}
+}
+
+// WithSigV4 signs every attempt of the requests of the client with signer, see WithSigner.
+func (c *Client) WithSigV4(signer *SigV4Signer) *Client {
+	return c.WithSigner(signer)
+}
+
+// Middleware returns the MiddlewareFunc which signs the requests,
+// once for all their attempts unlike WithSigV4.
+func (s *SigV4Signer) Middleware() MiddlewareFunc {
+	return func(c *Client, r *http.Request) (*Response, error) {
+		if err := s.Sign(r); err != nil {
+			return nil, err
+		}
+		return c.Next(r)
+	}
+}
+
+// Sign sets the Authorization, X-Amz-Date and X-Amz-Content-Sha256 headers of request.
+func (s *SigV4Signer) Sign(request *http.Request) error {
+	return s.signAt(request, time.Now())
+}
+
+// Presign returns the URL of request signed in its query string, valid for expires, up to 7 days.
+// Only the host and the X-Amz-* headers of request are signed and the payload is not.
+func (s *SigV4Signer) Presign(request *http.Request, expires time.Duration) (string, error) {
+	return s.presignAt(request, expires, time.Now())
+}
+
+func (s *SigV4Signer) signAt(request *http.Request, t time.Time) error {
+	credentials, err := s.credentials(request.Context())
+	if err != nil {
+		return err
+	}
+	t = t.UTC()
+	for _, k := range []string{HttpHeaderAuthorization, HttpHeaderAmzDate, HttpHeaderAmzSecurityToken} {
+		request.Header.Del(k)
+	}
+	request.Header.Set(HttpHeaderAmzDate, t.Format(sigV4TimeFormat))
+	if credentials.SessionToken != "" {
+		request.Header.Set(HttpHeaderAmzSecurityToken, credentials.SessionToken)
+	}
+	payloadHash, err := s.payloadHash(request)
+	if err != nil {
+		return err
+	}
+	if !s.DisableContentSHA256Header {
+		request.Header.Set(HttpHeaderAmzContentSha256, payloadHash)
+	}
+	_, signedHeaders, canonicalHeaders := sigV4Headers(request, false)
+	signature := s.signature(credentials, t, request, request.URL.Query(), canonicalHeaders, signedHeaders, payloadHash)
+	request.Header.Set(HttpHeaderAuthorization, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, credentials.AccessKeyID, s.scope(t), signedHeaders, signature))
+	return nil
+}
+
+func (s *SigV4Signer) presignAt(request *http.Request, expires time.Duration, t time.Time) (string, error) {
+	if expires <= 0 || expires > sigV4MaxExpires {
+		return "", fmt.Errorf("%w: expires must be between 1s and 7 days, got %s", ErrSigV4, expires)
+	}
+	credentials, err := s.credentials(request.Context())
+	if err != nil {
+		return "", err
+	}
+	t = t.UTC()
+	host, signedHeaders, canonicalHeaders := sigV4Headers(request, true)
+	query := request.URL.Query()
+	query.Del("X-Amz-Signature")
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	if credentials.SessionToken != "" {
+		query.Set(HttpHeaderAmzSecurityToken, credentials.SessionToken)
+	}
+	signature := s.signature(credentials, t, request, query, canonicalHeaders, signedHeaders, UnsignedPayload)
+	u := *request.URL
+	if request.Host == "" || request.Host == request.URL.Host {
+		u.Host = host
+	}
+	u.RawQuery = sigV4Query(query) + "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+func (s *SigV4Signer) credentials(ctx context.Context) (AWSCredentials, error) {
+	if s.Credentials == nil {
+		return AWSCredentials{}, fmt.Errorf("%w: no credentials", ErrSigV4)
+	}
+	credentials, err := s.Credentials.Credentials(ctx)
+	if err != nil {
+		return credentials, fmt.Errorf("%w: %v", ErrSigV4, err)
+	}
+	return credentials, nil
+}
+
+func (s *SigV4Signer) scope(t time.Time) string {
+	return t.Format(sigV4DateFormat) + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+// payloadHash returns the hex SHA-256 of the body of request, the X-Amz-Content-Sha256 header when it is set.
+func (s *SigV4Signer) payloadHash(request *http.Request) (string, error) {
+	if s.UnsignedPayload {
+		return UnsignedPayload, nil
+	}
+	if hash := request.Header.Get(HttpHeaderAmzContentSha256); hash != "" {
+		return hash, nil
+	}
+	h := sha256.New()
+	if err := copyBody(h, request); err != nil {
+		return "", fmt.Errorf("%w: hash the body: %v", ErrSigV4, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signature returns the signature of the canonical request.
+func (s *SigV4Signer) signature(credentials AWSCredentials, t time.Time, request *http.Request, query url.Values,
+	canonicalHeaders, signedHeaders, payloadHash string) string {
+	canonicalURI := request.URL.Path
+	if !s.DisableURIPathEscaping {
+		// The "." and ".." segments are removed, but for S3 whose keys may contain them.
+		canonicalURI = sigV4CleanPath(canonicalURI)
+	}
+	canonicalURI = sigV4Escape(canonicalURI, false)
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	if !s.DisableURIPathEscaping {
+		canonicalURI = sigV4Escape(canonicalURI, false)
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalURI,
+		sigV4Query(query),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(sigV4TimeFormat),
+		s.scope(t),
+		Sha256(canonicalRequest),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), t.Format(sigV4DateFormat))
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	return Hmac(sha256.New, key, []byte(data))
+}
+
+// sigV4CleanPath removes the "." and ".." segments and the repeated slashes of p,
+// keeping its trailing slash.
+func sigV4CleanPath(p string) string {
+	if p == "" {
+		return p
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// sigV4Headers returns the signed host, the signed header names and the canonical headers of request.
+// Presigned URLs are signed without the default port of their scheme, which browsers do not send,
+// and with only the host and the X-Amz-* headers.
+func sigV4Headers(request *http.Request, presign bool) (host, signedHeaders, canonicalHeaders string) {
+	host = request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	if h, port, err := net.SplitHostPort(host); presign && err == nil &&
+		((port == "80" && request.URL.Scheme == HttpSchemeName) || (port == "443" && request.URL.Scheme == HttpsSchemeName)) {
+		host = h
+	}
+	headers := map[string][]string{"host": {host}}
+	for k, vs := range request.Header {
+		name := strings.ToLower(k)
+		if sigV4IgnoredHeaders[name] || name == "host" || (presign && !strings.HasPrefix(name, "x-amz-")) {
+			continue
+		}
+		headers[name] = append(headers[name], vs...)
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		values := make([]string, 0, len(headers[name]))
+		for _, v := range headers[name] {
+			// The lines of folded values are separate values.
+			for _, line := range strings.Split(v, "\n") {
+				values = append(values, strings.Join(strings.Fields(line), " "))
+			}
+		}
+		b.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return host, strings.Join(names, ";"), b.String()
+}
+
+// sigV4Query returns the canonical query string of query, sorted by key and value.
+func sigV4Query(query url.Values) string {
+	escaped := make(map[string][]string, len(query))
+	keys := make([]string, 0, len(query))
+	for k, vs := range query {
+		key := sigV4Escape(k, true)
+		keys = append(keys, key)
+		for _, v := range vs {
+			escaped[key] = append(escaped[key], sigV4Escape(v, true))
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := escaped[key]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, key+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes every byte of s but the unreserved characters of RFC 3986,
+// and "/" unless encodeSlash is set.
+func sigV4Escape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
@@ -1,13 +1,9 @@
 package requests
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"net/url"
-	"os"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -128,90 +124,29 @@ func functionName(i interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 }
 
+// URIQuery parses uri and sets the values of query in its query string,
+// it returns nil when uri is invalid, see ParseURIQuery.
 func URIQuery(uri string, query ...url.Values) *url.URL {
-	u, _ := url.Parse(uri)
-	q := u.Query()
-	for _, value := range query {
-		for k := range value {
-			q.Set(k, value.Get(k))
-		}
-	}
-	u.RawQuery = q.Encode()
+	u, _ := ParseURIQuery(uri, query...)
 	return u
 }
 
-func UrlValues(uvs ...url.Values) url.Values {
-	query := url.Values{}
-	for _, value := range uvs {
-		for k := range value {
-			query.Set(k, value.Get(k))
-		}
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+	for k, v := range values {
+		clone[k] = append([]string(nil), v...)
 	}
-	return query
+	return clone
 }
 
-// HttpBuildQuery Generate get request parameters
-func HttpBuildQuery(data map[string]any) string {
-	var buf bytes.Buffer
-	for k, v := range data {
-		buildQuery(&buf, k, v)
-	}
-	return buf.String()
-}
-
-func buildQuery(buf *bytes.Buffer, key string, val any) {
-	if buf.Len() > 0 {
-		buf.WriteByte('&')
-	}
-	buf.WriteString(url.QueryEscape(key))
-	buf.WriteByte('=')
-	switch v := val.(type) {
-	case map[string]any:
-		for sk, sv := range v {
-			buildQuery(buf, fmt.Sprintf("%s[%s]", key, sk), sv)
-		}
-	case []any:
-		for _, item := range v {
-			buildQuery(buf, key, item)
-		}
-	default:
-		buf.WriteString(url.QueryEscape(fmt.Sprint(v)))
-	}
+// UrlValues merges uvs, the values of a key replace its values in the previous ones.
+func UrlValues(uvs ...url.Values) url.Values {
+	return MergeValues(url.Values{}, QueryMergeReplace, uvs...)
 }
 
-func buildFormBody(data url.Values) (w *multipart.Writer, body *bytes.Buffer, err error) {
-	body = new(bytes.Buffer)
-	w = multipart.NewWriter(body)
-	for k := range data {
-		v := data.Get(k)
-		if strings.Contains(v, HttpParamFileHolder) {
-			localPathFile := strings.TrimPrefix(v, HttpParamFileHolder)
-			var (
-				osFile   *os.File
-				ioWriter io.Writer
-			)
-			osFile, err = os.Open(localPathFile)
-			if err != nil {
-				return
-			}
-			ioWriter, err = w.CreateFormFile(k, k)
-			if err != nil {
-				return
-			}
-			_, err = io.Copy(ioWriter, osFile)
-			if err != nil {
-				return
-			}
-		} else {
-			err = w.WriteField(k, v)
-			if err != nil {
-				return
-			}
-		}
-	}
-	err = w.Close()
-	if err != nil {
-		return
-	}
-	return
+// HttpBuildQuery Generate get request parameters, sorted by key.
+// Nested maps are encoded as `a[b]=c` and slices repeat the key, see QueryEncoder.
+func HttpBuildQuery(data map[string]any) string {
+	query, _ := NewQueryEncoder().Encode(data)
+	return query
 }
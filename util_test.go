@@ -145,7 +145,8 @@ func TestHttpBuildQuery(t *testing.T) {
 		expect string
 	}{
 		{map[string]any{"a": "1"}, "a=1"},
-		//{map[string]any{"a": "1", "b": "2"}, "a=1&b=2"},
+		{map[string]any{"a": "1", "b": "2"}, "a=1&b=2"},
+		{map[string]any{"b": map[string]any{"d": 2, "c": 1}, "a": []any{"x", "y"}}, "a=x&a=y&b%5Bc%5D=1&b%5Bd%5D=2"},
 	} {
 		result := HttpBuildQuery(test.input)
 		if result != test.expect {